@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/nozzlium/halosuster/internal/client"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+// runBootstrapAdmin handles `halosuster bootstrap-admin`.
+func runBootstrapAdmin(args []string) error {
+	flags := flag.NewFlagSet(
+		"bootstrap-admin",
+		flag.ContinueOnError,
+	)
+	nip := flags.Uint64(
+		"nip",
+		0,
+		"NIP of the IT user",
+	)
+	name := flags.String(
+		"name",
+		"",
+		"name of the IT user",
+	)
+	password := flags.String(
+		"password",
+		"",
+		"password of the IT user, generated when empty",
+	)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		passwordBytes := make([]byte, 12)
+		_, err = rand.Read(passwordBytes)
+		if err != nil {
+			return err
+		}
+		*password = base64.RawURLEncoding.EncodeToString(
+			passwordBytes,
+		)
+	}
+
+	body := model.UserRegisterRequestBody{
+		NIP:      *nip,
+		Name:     *name,
+		Password: *password,
+	}
+	user, err := body.IsValid()
+	if err != nil {
+		var validation *model.ValidationError
+		if errors.As(err, &validation) {
+			fields := make([]string, 0, len(validation.Fields))
+			for _, field := range validation.Fields {
+				fields = append(
+					fields,
+					field.Field+" "+field.Message,
+				)
+			}
+			return fmt.Errorf(
+				"invalid admin details: %s",
+				strings.Join(fields, "; "),
+			)
+		}
+		return fmt.Errorf("invalid admin details: %w", err)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := client.InitDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	svc, err := newServices(
+		cfg,
+		db,
+	)
+	if err != nil {
+		return err
+	}
+	admin, err := svc.user.BootstrapAdmin(
+		context.Background(),
+		user,
+	)
+	if err != nil {
+		if errors.Is(err, constant.ErrConflict) {
+			return errors.New(
+				"an IT user or a user with this NIP already exists",
+			)
+		}
+		return err
+	}
+
+	fmt.Printf("created IT user %s (NIP %d)\n", admin.UserID, admin.NIP)
+	if generated {
+		fmt.Printf("generated password: %s\n", *password)
+	}
+
+	return nil
+}
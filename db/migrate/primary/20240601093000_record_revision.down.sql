@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_record_latest;
+DROP INDEX IF EXISTS idx_record_revision;
+
+-- only the latest revision of every record survives the rollback
+DELETE FROM "records" WHERE "superseded_at" IS NOT NULL;
+
+ALTER TABLE "records"
+  DROP COLUMN IF EXISTS "superseded_at",
+  DROP COLUMN IF EXISTS "amend_reason",
+  DROP COLUMN IF EXISTS "revision",
+  DROP COLUMN IF EXISTS "record_id";
@@ -0,0 +1,12 @@
+ALTER TABLE "records"
+  ADD COLUMN IF NOT EXISTS "record_id" uuid,
+  ADD COLUMN IF NOT EXISTS "revision" integer NOT NULL DEFAULT 1,
+  ADD COLUMN IF NOT EXISTS "amend_reason" varchar(500),
+  ADD COLUMN IF NOT EXISTS "superseded_at" timestamp;
+
+UPDATE "records" SET "record_id" = "id" WHERE "record_id" IS NULL;
+
+ALTER TABLE "records" ALTER COLUMN "record_id" SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_record_revision ON records(record_id, revision);
+CREATE INDEX IF NOT EXISTS idx_record_latest ON records(record_id) WHERE superseded_at IS NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_user_roles_role_name;
+DROP TABLE IF EXISTS "user_roles";
+DROP TABLE IF EXISTS "role_permissions";
+DROP TABLE IF EXISTS "permissions";
+DROP TABLE IF EXISTS "roles";
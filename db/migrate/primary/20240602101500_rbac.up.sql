@@ -0,0 +1,68 @@
+CREATE TABLE IF NOT EXISTS "roles" (
+  "name" varchar(30) NOT NULL,
+  "description" varchar(255),
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "permissions" (
+  "name" varchar(50) NOT NULL,
+  "description" varchar(255),
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("name")
+);
+
+CREATE TABLE IF NOT EXISTS "role_permissions" (
+  "role_name" varchar(30) NOT NULL,
+  "permission_name" varchar(50) NOT NULL,
+  PRIMARY KEY ("role_name", "permission_name"),
+  FOREIGN KEY ("role_name") REFERENCES "roles" ("name") ON DELETE CASCADE,
+  FOREIGN KEY ("permission_name") REFERENCES "permissions" ("name") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "user_roles" (
+  "user_id" uuid NOT NULL,
+  "role_name" varchar(30) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id", "role_name"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE,
+  FOREIGN KEY ("role_name") REFERENCES "roles" ("name") ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_name ON user_roles(role_name);
+
+INSERT INTO "roles" ("name", "description") VALUES
+  ('it', 'IT staff managing nurse accounts'),
+  ('nurse', 'Nurse managing patients and medical records')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('user:read', 'List and search users'),
+  ('nurse:manage', 'Register, edit, delete and grant access to nurses'),
+  ('patient:read', 'View patients'),
+  ('patient:write', 'Register, edit and delete patients'),
+  ('record:read', 'View medical records'),
+  ('record:write', 'Create and amend medical records')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'user:read'),
+  ('it', 'nurse:manage'),
+  ('it', 'patient:read'),
+  ('it', 'patient:write'),
+  ('it', 'record:read'),
+  ('it', 'record:write'),
+  ('nurse', 'patient:read'),
+  ('nurse', 'patient:write'),
+  ('nurse', 'record:read'),
+  ('nurse', 'record:write')
+ON CONFLICT DO NOTHING;
+
+-- existing accounts still carry their role in the employee ID prefix
+INSERT INTO "user_roles" ("user_id", "role_name")
+  SELECT "id", 'it' FROM "users" WHERE "employee_id" LIKE '615%'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "user_roles" ("user_id", "role_name")
+  SELECT "id", 'nurse' FROM "users" WHERE "employee_id" LIKE '303%'
+ON CONFLICT DO NOTHING;
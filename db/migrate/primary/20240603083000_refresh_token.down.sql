@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "revoked_access_tokens";
+DROP INDEX IF EXISTS idx_refresh_token_user;
+DROP INDEX IF EXISTS idx_refresh_token_family;
+DROP INDEX IF EXISTS idx_refresh_token_hash;
+DROP TABLE IF EXISTS "refresh_tokens";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "token_version";
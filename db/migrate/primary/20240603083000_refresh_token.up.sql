@@ -0,0 +1,25 @@
+ALTER TABLE "users"
+  ADD COLUMN IF NOT EXISTS "token_version" integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "family_id" uuid NOT NULL,
+  "token_hash" varchar(64) NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp NULL DEFAULT NULL,
+  "replaced_by" uuid NULL DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user ON refresh_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS "revoked_access_tokens" (
+  "jti" uuid NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  PRIMARY KEY ("jti")
+);
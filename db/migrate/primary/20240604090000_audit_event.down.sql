@@ -0,0 +1,9 @@
+DELETE FROM "role_permissions" WHERE "permission_name" = 'audit:read';
+DELETE FROM "permissions" WHERE "name" = 'audit:read';
+
+DROP TRIGGER IF EXISTS trg_audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+DROP INDEX IF EXISTS idx_audit_event_created_at;
+DROP INDEX IF EXISTS idx_audit_event_target;
+DROP INDEX IF EXISTS idx_audit_event_actor;
+DROP TABLE IF EXISTS "audit_events";
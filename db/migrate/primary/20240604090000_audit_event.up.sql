@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS "audit_events" (
+  "id" uuid NOT NULL,
+  "actor_id" uuid NULL DEFAULT NULL,
+  "action" varchar(10) NOT NULL,
+  "target_type" varchar(20) NOT NULL,
+  "target_id" varchar(64) NOT NULL,
+  "request_id" varchar(64) NULL DEFAULT NULL,
+  "ip" varchar(45) NULL DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_event_actor ON audit_events(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_event_target ON audit_events(target_type, target_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_event_created_at ON audit_events(created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_events_append_only ON audit_events;
+CREATE TRIGGER trg_audit_events_append_only
+  BEFORE UPDATE OR DELETE ON audit_events
+  FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('audit:read', 'Query the audit trail')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'audit:read')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_user_employee_id_active;
@@ -0,0 +1,5 @@
+-- enforces what UserService checks before inserting, so two concurrent
+-- registrations with the same NIP cannot both succeed
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_employee_id_active
+  ON users(employee_id)
+  WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DELETE FROM "role_permissions" WHERE "permission_name" = 'image:upload';
+DELETE FROM "permissions" WHERE "name" = 'image:upload';
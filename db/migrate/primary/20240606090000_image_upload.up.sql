@@ -0,0 +1,8 @@
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('image:upload', 'Upload identity card scans')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'image:upload'),
+  ('nurse', 'image:upload')
+ON CONFLICT DO NOTHING;
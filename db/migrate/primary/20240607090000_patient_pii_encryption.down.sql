@@ -0,0 +1,32 @@
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "patients" WHERE "key_version" IS NOT NULL) THEN
+    RAISE EXCEPTION 'patients hold encrypted data, which cannot be decrypted by a migration';
+  END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_patient_key_version;
+DROP INDEX IF EXISTS idx_patient_name_index;
+DROP INDEX IF EXISTS idx_patient_phone_number_index;
+
+ALTER TABLE "patients"
+  DROP COLUMN IF EXISTS "name_index",
+  DROP COLUMN IF EXISTS "phone_number_index",
+  DROP COLUMN IF EXISTS "key_version",
+  DROP COLUMN IF EXISTS "data_key",
+  DROP COLUMN IF EXISTS "birthdate_enc",
+  DROP COLUMN IF EXISTS "name_enc",
+  DROP COLUMN IF EXISTS "phone_number_enc",
+  DROP COLUMN IF EXISTS "identity_number_enc",
+  ALTER COLUMN "birthdate" SET NOT NULL,
+  ALTER COLUMN "name" SET NOT NULL,
+  ALTER COLUMN "phone_number" SET NOT NULL;
+
+ALTER TABLE "records" DROP CONSTRAINT IF EXISTS "records_identity_number_fkey";
+ALTER TABLE "records" ALTER COLUMN "identity_number" TYPE varchar(16);
+ALTER TABLE "patients" ALTER COLUMN "identity_number" TYPE varchar(16);
+ALTER TABLE "records"
+  ADD CONSTRAINT "records_identity_number_fkey"
+  FOREIGN KEY ("identity_number") REFERENCES "patients" ("identity_number")
+  ON DELETE CASCADE;
@@ -0,0 +1,29 @@
+-- identity_number becomes the blind index of the identity number, records
+-- follow it through ON UPDATE CASCADE when existing rows are encrypted by
+-- `halosuster pii backfill`
+ALTER TABLE "records" DROP CONSTRAINT IF EXISTS "records_identity_number_fkey";
+ALTER TABLE "patients" ALTER COLUMN "identity_number" TYPE varchar(64);
+ALTER TABLE "records" ALTER COLUMN "identity_number" TYPE varchar(64);
+ALTER TABLE "records"
+  ADD CONSTRAINT "records_identity_number_fkey"
+  FOREIGN KEY ("identity_number") REFERENCES "patients" ("identity_number")
+  ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- the plaintext columns stay until every row has been backfilled, new
+-- rows leave them empty
+ALTER TABLE "patients"
+  ALTER COLUMN "phone_number" DROP NOT NULL,
+  ALTER COLUMN "name" DROP NOT NULL,
+  ALTER COLUMN "birthdate" DROP NOT NULL,
+  ADD COLUMN IF NOT EXISTS "identity_number_enc" bytea,
+  ADD COLUMN IF NOT EXISTS "phone_number_enc" bytea,
+  ADD COLUMN IF NOT EXISTS "name_enc" bytea,
+  ADD COLUMN IF NOT EXISTS "birthdate_enc" bytea,
+  ADD COLUMN IF NOT EXISTS "data_key" bytea,
+  ADD COLUMN IF NOT EXISTS "key_version" integer,
+  ADD COLUMN IF NOT EXISTS "phone_number_index" text[],
+  ADD COLUMN IF NOT EXISTS "name_index" text[];
+
+CREATE INDEX IF NOT EXISTS idx_patient_phone_number_index ON patients USING gin (phone_number_index);
+CREATE INDEX IF NOT EXISTS idx_patient_name_index ON patients USING gin (name_index);
+CREATE INDEX IF NOT EXISTS idx_patient_key_version ON patients(key_version);
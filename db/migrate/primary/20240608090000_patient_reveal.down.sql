@@ -0,0 +1,2 @@
+DELETE FROM "role_permissions" WHERE "permission_name" = 'patient:reveal';
+DELETE FROM "permissions" WHERE "name" = 'patient:reveal';
@@ -0,0 +1,7 @@
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('patient:reveal', 'See the unmasked identity and phone numbers of patients')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'patient:reveal')
+ON CONFLICT DO NOTHING;
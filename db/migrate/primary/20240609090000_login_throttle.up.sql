@@ -0,0 +1,12 @@
+-- failed logins are counted per NIP ("nip:<nip>") and per client IP
+-- ("ip:<ip>"), the NIP does not need to belong to an existing account
+CREATE TABLE IF NOT EXISTS "login_throttles" (
+  "key" varchar(100) NOT NULL,
+  "failed_attempts" integer NOT NULL DEFAULT 0,
+  "lockouts" integer NOT NULL DEFAULT 0,
+  "locked_until" timestamp NULL DEFAULT NULL,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("key")
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttle_updated_at ON login_throttles(updated_at);
@@ -0,0 +1,4 @@
+DELETE FROM "role_permissions" WHERE "permission_name" = 'user:2fa';
+DELETE FROM "permissions" WHERE "name" = 'user:2fa';
+DROP TABLE IF EXISTS "user_recovery_codes";
+DROP TABLE IF EXISTS "user_totp";
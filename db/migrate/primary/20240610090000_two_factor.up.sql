@@ -0,0 +1,31 @@
+-- a secret without enabled_at is an enrollment waiting to be verified,
+-- last_step keeps a code from being used twice
+CREATE TABLE IF NOT EXISTS "user_totp" (
+  "user_id" uuid NOT NULL,
+  "secret" varchar(64) NOT NULL,
+  "enabled_at" timestamp NULL DEFAULT NULL,
+  "last_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "user_recovery_codes" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "code_hash" varchar(64) NOT NULL,
+  "used_at" timestamp NULL DEFAULT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_recovery_code_hash ON user_recovery_codes(user_id, code_hash);
+
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('user:2fa', 'Enroll in two-factor authentication')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'user:2fa')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- set whenever an IT user hands a nurse a password, cleared once the nurse
+-- picks their own
+ALTER TABLE "users"
+  ADD COLUMN IF NOT EXISTS "must_change_password" boolean NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+UPDATE "users" SET "password" = '' WHERE "password" IS NULL;
@@ -0,0 +1,3 @@
+-- a user without a password cannot log in, nurses registered before they
+-- were granted access used to get an empty one
+UPDATE "users" SET "password" = NULL WHERE "password" = '';
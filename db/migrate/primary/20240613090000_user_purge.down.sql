@@ -0,0 +1,15 @@
+ALTER TABLE "records"
+  DROP CONSTRAINT IF EXISTS "records_user_id_fkey",
+  ADD CONSTRAINT "records_user_id_fkey"
+    FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "patients"
+  DROP CONSTRAINT IF EXISTS "patients_user_id_fkey",
+  ADD CONSTRAINT "patients_user_id_fkey"
+    FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+DELETE FROM "role_permissions" WHERE "permission_name" = 'user:purge';
+DELETE FROM "permissions" WHERE "name" = 'user:purge';
+
+ALTER TABLE "users"
+  DROP COLUMN IF EXISTS "purged_at";
@@ -0,0 +1,28 @@
+-- a purged user whose records were not reassigned stays behind as an
+-- anonymous row the records still point at
+ALTER TABLE "users"
+  ADD COLUMN IF NOT EXISTS "purged_at" timestamp;
+
+-- deleting a user drops their password, so restoring them does not bring
+-- back access; users deleted before that lose theirs now
+UPDATE "users" SET "password" = NULL WHERE "deleted_at" IS NOT NULL;
+
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('user:purge', 'Permanently remove deleted users')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'user:purge')
+ON CONFLICT DO NOTHING;
+
+-- deleting a user must never take medical data with it, purging reassigns
+-- or anonymizes first
+ALTER TABLE "patients"
+  DROP CONSTRAINT IF EXISTS "patients_user_id_fkey",
+  ADD CONSTRAINT "patients_user_id_fkey"
+    FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE RESTRICT;
+
+ALTER TABLE "records"
+  DROP CONSTRAINT IF EXISTS "records_user_id_fkey",
+  ADD CONSTRAINT "records_user_id_fkey"
+    FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE RESTRICT;
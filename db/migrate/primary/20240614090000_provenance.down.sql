@@ -0,0 +1,7 @@
+ALTER TABLE "records"
+  DROP CONSTRAINT IF EXISTS "records_created_by_fkey",
+  DROP COLUMN IF EXISTS "created_by";
+
+ALTER TABLE "patients"
+  DROP CONSTRAINT IF EXISTS "patients_updated_by_fkey",
+  DROP COLUMN IF EXISTS "updated_by";
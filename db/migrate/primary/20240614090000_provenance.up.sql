@@ -0,0 +1,39 @@
+-- patients.user_id is who registered the patient, updated_by who last
+-- changed them
+ALTER TABLE "patients"
+  ADD COLUMN IF NOT EXISTS "updated_by" uuid;
+
+UPDATE "patients" SET "updated_by" = "user_id" WHERE "updated_by" IS NULL;
+
+ALTER TABLE "patients"
+  ALTER COLUMN "updated_by" SET NOT NULL,
+  ADD CONSTRAINT "patients_updated_by_fkey"
+    FOREIGN KEY ("updated_by") REFERENCES "users" ("id") ON DELETE RESTRICT;
+
+-- records.user_id is the author of a revision, created_by the author of
+-- the first one, carried along by every amendment
+ALTER TABLE "records"
+  ADD COLUMN IF NOT EXISTS "created_by" uuid;
+
+UPDATE "records" r SET "created_by" = coalesce(
+  (
+    SELECT f."user_id"
+    FROM "records" f
+    WHERE f."record_id" = r."record_id" AND f."revision" = 1
+  ),
+  r."user_id"
+)
+WHERE r."created_by" IS NULL;
+
+ALTER TABLE "records"
+  ALTER COLUMN "created_by" SET NOT NULL,
+  ADD CONSTRAINT "records_created_by_fkey"
+    FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE RESTRICT;
+
+-- IT users register themselves
+UPDATE "users" SET "created_by" = "id"
+WHERE "created_by" IS NULL
+  AND EXISTS (
+    SELECT 1 FROM "user_roles" ur
+    WHERE ur."user_id" = "users"."id" AND ur."role_name" = 'it'
+  );
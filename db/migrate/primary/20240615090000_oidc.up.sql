@@ -0,0 +1,25 @@
+-- a single sign-on started at the OpenID provider, taken once by the
+-- callback; the state itself only lives in the user's browser
+CREATE TABLE IF NOT EXISTS "oidc_login_states" (
+  "state_hash" varchar(64) NOT NULL,
+  "nonce" varchar(64) NOT NULL,
+  "code_verifier" varchar(64) NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("state_hash")
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_state_expires_at ON oidc_login_states(expires_at);
+
+-- the directory account a user signs in with, linked by NIP on the first
+-- single sign-on; a user has at most one per provider
+CREATE TABLE IF NOT EXISTS "user_identities" (
+  "issuer" varchar(255) NOT NULL,
+  "subject" varchar(255) NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("issuer", "subject"),
+  FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identity_user ON user_identities(user_id, issuer);
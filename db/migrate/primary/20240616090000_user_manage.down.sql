@@ -0,0 +1,2 @@
+DELETE FROM "role_permissions" WHERE "permission_name" = 'user:manage';
+DELETE FROM "permissions" WHERE "name" = 'user:manage';
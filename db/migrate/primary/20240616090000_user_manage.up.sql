@@ -0,0 +1,9 @@
+-- IT users are no longer registered by themselves, the first one comes
+-- from `halosuster bootstrap-admin` and registers the others
+INSERT INTO "permissions" ("name", "description") VALUES
+  ('user:manage', 'Register IT users')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "role_permissions" ("role_name", "permission_name") VALUES
+  ('it', 'user:manage')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,13 @@
+-- whether a user may log in at all, with a password or through single
+-- sign-on, instead of inferring it from their password and linked
+-- directory accounts
+ALTER TABLE "users"
+  ADD COLUMN IF NOT EXISTS "has_access" boolean NOT NULL DEFAULT false;
+
+UPDATE "users" SET "has_access" = (
+  "password" IS NOT NULL OR EXISTS (
+    SELECT 1
+    FROM "user_identities" ui
+    WHERE ui."user_id" = "users"."id"
+  )
+) AND "deleted_at" IS NULL;
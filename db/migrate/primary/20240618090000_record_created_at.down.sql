@@ -0,0 +1 @@
+UPDATE "records" SET "created_at" = "updated_at" WHERE "revision" > 1;
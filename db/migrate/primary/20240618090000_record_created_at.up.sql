@@ -0,0 +1,7 @@
+-- records.created_at is when the first revision was written, carried along
+-- by every amendment like created_by; updated_at is when each revision was
+UPDATE "records" r SET "created_at" = f."created_at"
+FROM "records" f
+WHERE f."record_id" = r."record_id"
+  AND f."revision" = 1
+  AND r."revision" > 1;
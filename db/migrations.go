@@ -0,0 +1,9 @@
+// Package db embeds the SQL migrations.
+package db
+
+import "embed"
+
+const PrimaryMigrationsDir = "migrate/primary"
+
+//go:embed migrate/primary/*.sql
+var PrimaryMigrations embed.FS
@@ -0,0 +1,66 @@
+// Package auth carries the principal and request of ctx to the services.
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type Principal struct {
+	UserID uuid.UUID
+	NIP    string
+	Roles  []string
+	// TokenID, TokenVersion and ExpiresAt identify the session
+	TokenID      uuid.UUID
+	TokenVersion int
+	ExpiresAt    time.Time
+}
+
+func (p Principal) Session() model.Session {
+	return model.Session{
+		UserID:       p.UserID,
+		TokenID:      p.TokenID,
+		TokenVersion: p.TokenVersion,
+		ExpiresAt:    p.ExpiresAt,
+	}
+}
+
+type principalKey struct{}
+
+func WithPrincipal(
+	ctx context.Context,
+	principal Principal,
+) context.Context {
+	return context.WithValue(
+		ctx,
+		principalKey{},
+		principal,
+	)
+}
+
+// PrincipalFrom fails with constant.ErrUnauthorized when nobody is logged in.
+func PrincipalFrom(
+	ctx context.Context,
+) (Principal, error) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	if !ok || principal.UserID == uuid.Nil {
+		return Principal{}, constant.ErrUnauthorized
+	}
+
+	return principal, nil
+}
+
+func UserID(
+	ctx context.Context,
+) (uuid.UUID, error) {
+	principal, err := PrincipalFrom(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return principal.UserID, nil
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "context"
+
+type Request struct {
+	ID string
+	IP string
+}
+
+type requestKey struct{}
+
+func WithRequest(
+	ctx context.Context,
+	request Request,
+) context.Context {
+	return context.WithValue(
+		ctx,
+		requestKey{},
+		request,
+	)
+}
+
+// RequestFrom is empty outside of HTTP requests.
+func RequestFrom(
+	ctx context.Context,
+) Request {
+	request, _ := ctx.Value(requestKey{}).(Request)
+	return request
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nozzlium/halosuster/internal/config"
 )
 
 func InitDB(
 	cfg config.DBConfig,
-) (*pgx.Conn, error) {
+) (*pgxpool.Pool, error) {
 	dbURI := fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?%s",
 		cfg.DBUsername,
@@ -21,13 +21,36 @@ func InitDB(
 		cfg.DBParams,
 	)
 
-	conn, err := pgx.Connect(
-		context.Background(),
+	poolConfig, err := pgxpool.ParseConfig(
 		dbURI,
 	)
 	if err != nil {
 		return nil, err
 	}
+	poolConfig.MaxConns = cfg.DBMaxConns
+	poolConfig.MinConns = cfg.DBMinConns
+	poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	poolConfig.ConnConfig.ConnectTimeout = cfg.DBConnectTimeout
+
+	pool, err := pgxpool.NewWithConfig(
+		context.Background(),
+		poolConfig,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		cfg.DBConnectTimeout,
+	)
+	defer cancel()
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
 
-	return conn, nil
+	return pool, nil
 }
@@ -1,9 +1,29 @@
 package config
 
+import "time"
+
 type Config struct {
-	DB         DBConfig
-	JWTSecret  string `json:"JWT_SECRET"`
-	BCryptSalt uint8  `json:"BCRYPT_SALT"`
+	DB              DBConfig
+	Storage         StorageConfig
+	PII             PIIConfig
+	Login           LoginThrottleConfig
+	JWT             JWTConfig
+	BCryptSalt      uint8         `json:"BCRYPT_SALT"`
+	AccessTokenTTL  time.Duration `json:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `json:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	TwoFactor       TwoFactorConfig
+	PasswordPolicy  PasswordPolicyConfig
+	OIDC            OIDCConfig
+}
+
+type JWTConfig struct {
+	// JWTKeys is "kid:path,..." of PEM private keys
+	JWTKeys        string `json:"JWT_KEYS"`
+	JWTActiveKeyID string `json:"JWT_ACTIVE_KEY_ID"`
+	// JWTSecret only verifies old tokens once JWTKeys is set
+	JWTSecret   string `json:"JWT_SECRET"`
+	JWTIssuer   string `json:"JWT_ISSUER" envDefault:"halosuster"`
+	JWTAudience string `json:"JWT_AUDIENCE" envDefault:"halosuster-api"`
 }
 
 type DBConfig struct {
@@ -13,4 +33,64 @@ type DBConfig struct {
 	DBUsername string `json:"DB_USERNAME"`
 	DBPassword string `json:"DB_PASSWORD"`
 	DBParams   string `json:"DB_PARAMS"`
+
+	DBMaxConns        int32         `json:"DB_MAX_CONNS" envDefault:"10"`
+	DBMinConns        int32         `json:"DB_MIN_CONNS" envDefault:"0"`
+	DBMaxConnLifetime time.Duration `json:"DB_MAX_CONN_LIFETIME" envDefault:"1h"`
+	DBMaxConnIdleTime time.Duration `json:"DB_MAX_CONN_IDLE_TIME" envDefault:"30m"`
+	DBConnectTimeout  time.Duration `json:"DB_CONNECT_TIMEOUT" envDefault:"10s"`
+}
+
+type StorageConfig struct {
+	StorageDriver    string `json:"STORAGE_DRIVER" envDefault:"local"`
+	StoragePublicURL string `json:"STORAGE_PUBLIC_URL" envDefault:"http://localhost:8080/uploads"`
+	StorageLocalDir  string `json:"STORAGE_LOCAL_DIR" envDefault:"./uploads"`
+	ImageMaxSize     int64  `json:"IMAGE_MAX_SIZE" envDefault:"2097152"`
+
+	S3Endpoint        string `json:"S3_ENDPOINT"`
+	S3Region          string `json:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket          string `json:"S3_BUCKET"`
+	S3AccessKeyID     string `json:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `json:"S3_SECRET_ACCESS_KEY"`
+}
+
+type PIIConfig struct {
+	// PIIKeys lists the key-encryption keys as "version:base64,..."
+	PIIKeys             string `json:"PII_KEYS"`
+	PIIActiveKeyVersion int    `json:"PII_ACTIVE_KEY_VERSION"`
+	PIIIndexKey         string `json:"PII_INDEX_KEY"`
+}
+
+type LoginThrottleConfig struct {
+	LoginMaxAttempts   int           `json:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	LoginIPMaxAttempts int           `json:"LOGIN_IP_MAX_ATTEMPTS" envDefault:"20"`
+	LoginLockout       time.Duration `json:"LOGIN_LOCKOUT" envDefault:"1m"`
+	LoginLockoutMax    time.Duration `json:"LOGIN_LOCKOUT_MAX" envDefault:"1h"`
+	LoginAttemptWindow time.Duration `json:"LOGIN_ATTEMPT_WINDOW" envDefault:"15m"`
+}
+
+type TwoFactorConfig struct {
+	TOTPIssuer            string        `json:"TOTP_ISSUER" envDefault:"Halo Suster"`
+	TwoFactorChallengeTTL time.Duration `json:"TWO_FACTOR_CHALLENGE_TTL" envDefault:"5m"`
+}
+
+type PasswordPolicyConfig struct {
+	PasswordMinLength     int  `json:"PASSWORD_MIN_LENGTH" envDefault:"10"`
+	PasswordRequireUpper  bool `json:"PASSWORD_REQUIRE_UPPER" envDefault:"true"`
+	PasswordRequireLower  bool `json:"PASSWORD_REQUIRE_LOWER" envDefault:"true"`
+	PasswordRequireDigit  bool `json:"PASSWORD_REQUIRE_DIGIT" envDefault:"true"`
+	PasswordRequireSymbol bool `json:"PASSWORD_REQUIRE_SYMBOL" envDefault:"false"`
+}
+
+// OIDCConfig enables single sign-on when OIDCIssuer is set.
+type OIDCConfig struct {
+	OIDCIssuer          string        `json:"OIDC_ISSUER"`
+	OIDCClientID        string        `json:"OIDC_CLIENT_ID"`
+	OIDCClientSecret    string        `json:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL     string        `json:"OIDC_REDIRECT_URL"`
+	OIDCScopes          string        `json:"OIDC_SCOPES" envDefault:"openid profile"`
+	OIDCNIPClaim        string        `json:"OIDC_NIP_CLAIM" envDefault:"nip"`
+	OIDCNameClaim       string        `json:"OIDC_NAME_CLAIM" envDefault:"name"`
+	OIDCProvisionNurses bool          `json:"OIDC_PROVISION_NURSES" envDefault:"false"`
+	OIDCStateTTL        time.Duration `json:"OIDC_STATE_TTL" envDefault:"10m"`
 }
@@ -0,0 +1,19 @@
+package constant
+
+const (
+	AuditActionCreate = "create"
+	AuditActionRead   = "read"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	// AuditActionReveal is an unmasked read of a patient's PII
+	AuditActionReveal  = "reveal"
+	AuditActionUnlock  = "unlock"
+	AuditActionRestore = "restore"
+	AuditActionPurge   = "purge"
+)
+
+const (
+	AuditTargetPatient = "patient"
+	AuditTargetRecord  = "record"
+	AuditTargetUser    = "user"
+)
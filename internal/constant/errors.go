@@ -45,4 +45,12 @@ var (
 	ErrInvalidChange = errors.New(
 		"invalid change",
 	)
+
+	ErrAccountLocked = errors.New(
+		"account locked",
+	)
+
+	ErrTooManyAttempts = errors.New(
+		"too many attempts",
+	)
 )
@@ -0,0 +1,21 @@
+package constant
+
+const (
+	RoleIT    = "it"
+	RoleNurse = "nurse"
+)
+
+const (
+	PermissionUserRead      = "user:read"
+	PermissionNurseManage   = "nurse:manage"
+	PermissionPatientRead   = "patient:read"
+	PermissionPatientWrite  = "patient:write"
+	PermissionRecordRead    = "record:read"
+	PermissionRecordWrite   = "record:write"
+	PermissionAuditRead     = "audit:read"
+	PermissionImageUpload   = "image:upload"
+	PermissionPatientReveal = "patient:reveal"
+	PermissionTwoFactor     = "user:2fa"
+	PermissionUserPurge     = "user:purge"
+	PermissionUserManage    = "user:manage"
+)
@@ -0,0 +1,45 @@
+package constant
+
+// Validation codes are stable, clients key their own messages on them.
+const (
+	ValidationRequired = "required"
+	ValidationLength   = "length"
+	ValidationFormat   = "format"
+	ValidationOneOf    = "one_of"
+	ValidationFileSize = "file_size"
+	// ValidationCharacter reports a password missing a character class
+	ValidationCharacter = "character"
+	// ValidationReused reports a new password equal to the current one
+	ValidationReused = "reused"
+	// ValidationUnknown reports a query parameter that is not supported
+	ValidationUnknown = "unknown"
+)
+
+// Character classes reported with ValidationCharacter.
+const (
+	CharacterUpper  = "upper"
+	CharacterLower  = "lower"
+	CharacterDigit  = "digit"
+	CharacterSymbol = "symbol"
+)
+
+const (
+	FormatNIP            = "nip"
+	FormatITNIP          = "it_nip"
+	FormatIdentityNumber = "identity_number"
+	FormatPhoneNumber    = "phone_number"
+	FormatDate           = "date"
+	FormatTime           = "time"
+	FormatURL            = "url"
+	FormatUUID           = "uuid"
+	FormatImage          = "image"
+	FormatTOTPCode       = "totp_code"
+	FormatNumber         = "number"
+	FormatSort           = "sort"
+	FormatFilter         = "filter"
+	FormatCursor         = "cursor"
+	// FormatCurrentPassword reports an old password that does not match
+	FormatCurrentPassword = "current_password"
+	// FormatPurgedNIP is a NIP other than the purged user's
+	FormatPurgedNIP = "purged_nip"
+)
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(
+	auditService *service.AuditService,
+) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (h *AuditHandler) FindAll(
+	ctx *fiber.Ctx,
+) error {
+	var queries model.AuditQuery
+	ctx.QueryParser(&queries)
+	queries.Offset = ctx.QueryInt(
+		"offset",
+		0,
+	)
+	queries.Limit = queryLimit(ctx)
+
+	err := queries.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid query",
+				detail: fmt.Sprintf(
+					"find audit events; invalid query: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	queries.List, err = model.ParseListQuery(
+		ctx.Query("sort"),
+		ctx.Queries(),
+		model.AuditQueryFields,
+		model.AuditKeyset,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find audit events; invalid list query: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, meta, err := h.auditService.FindAll(
+		ctx.UserContext(),
+		queries,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find audit events; error finding audit events: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+		"meta":    meta,
+	})
+}
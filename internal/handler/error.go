@@ -1,53 +1,71 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"log"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/i18n"
+	"github.com/nozzlium/halosuster/internal/model"
 )
 
+var errorStatus = map[string]int{
+	i18n.CodeNotFound:        fiber.StatusNotFound,
+	i18n.CodeConflict:        fiber.StatusConflict,
+	i18n.CodeUnauthorized:    fiber.StatusUnauthorized,
+	i18n.CodeBadInput:        fiber.StatusBadRequest,
+	i18n.CodeInvalidBody:     fiber.StatusBadRequest,
+	i18n.CodeAccountLocked:   fiber.StatusLocked,
+	i18n.CodeTooManyAttempts: fiber.StatusTooManyRequests,
+}
+
+// HandleError only logs the handler's own message.
 func HandleError(
 	ctx *fiber.Ctx,
 	err ErrorResponse,
 ) error {
-	log.Printf("error: %v", err.detail)
-	switch err.error {
-	case constant.ErrNotFound:
-		return ctx.Status(fiber.StatusNotFound).
-			JSON(fiber.Map{
-				"message": err.message,
-			})
-	case constant.ErrConflict:
-		return ctx.Status(fiber.StatusConflict).
-			JSON(fiber.Map{
-				"message": err.message,
-			})
-	case constant.ErrUnauthorized:
-		return ctx.Status(fiber.StatusUnauthorized).
-			JSON(fiber.Map{
-				"message": err.message,
-			})
-	case constant.ErrBadInput,
-		constant.ErrInvalidBody,
-		constant.ErrInsufficientFund,
-		constant.ErrInvalidChange,
-		constant.ErrInsufficientStock:
-		return ctx.Status(fiber.StatusBadRequest).
-			JSON(fiber.Map{
-				"message": err.message,
-			})
-	default:
+	log.Printf("error: %s: %v", err.message, err.detail)
+
+	code := i18n.Code(err.error)
+	status, ok := errorStatus[code]
+	if !ok {
 		log.Printf(
 			"internal error: %v",
 			err.error,
 		)
-		return ctx.Status(fiber.StatusInternalServerError).
-			JSON(fiber.Map{
-				"message": fmt.Sprint("internal server error", err.Error()),
-			})
+		status = fiber.StatusInternalServerError
 	}
+
+	lang := i18n.Negotiate(
+		ctx.Get(fiber.HeaderAcceptLanguage),
+	)
+	ctx.Set(
+		fiber.HeaderContentLanguage,
+		string(lang),
+	)
+	body := fiber.Map{
+		"message": i18n.Message(lang, code),
+		"code":    code,
+	}
+
+	var validation *model.ValidationError
+	if errors.As(err.error, &validation) {
+		body["errors"] = validation.Localize(lang)
+	}
+
+	var throttle *model.LoginThrottleError
+	if errors.As(err.error, &throttle) {
+		ctx.Set(
+			fiber.HeaderRetryAfter,
+			strconv.Itoa(
+				int(math.Ceil(throttle.RetryAfter.Seconds())),
+			),
+		)
+	}
+
+	return ctx.Status(status).JSON(body)
 }
 
 type ErrorResponse struct {
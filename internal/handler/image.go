@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/service"
+)
+
+type ImageHandler struct {
+	imageService *service.ImageService
+}
+
+func NewImageHandler(
+	imageService *service.ImageService,
+) *ImageHandler {
+	return &ImageHandler{
+		imageService: imageService,
+	}
+}
+
+func (h *ImageHandler) Upload(
+	ctx *fiber.Ctx,
+) error {
+	var image model.ImageUpload
+	fileHeader, err := ctx.FormFile("file")
+	if err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return HandleError(
+				ctx,
+				ErrorResponse{
+					error:   err,
+					message: "failed to read file",
+					detail: fmt.Sprintf(
+						"image upload; failed to open file: %v",
+						err,
+					),
+				},
+			)
+		}
+		defer file.Close()
+
+		image.Size = fileHeader.Size
+		image.Content = file
+	}
+
+	data, err := h.imageService.Upload(
+		ctx.UserContext(),
+		image,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"image upload; error uploading image: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "File uploaded successfully",
+		"data":    data,
+	})
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/service"
+)
+
+// jwksMaxAge is how long a key must be published before it becomes active.
+const jwksMaxAge = "max-age=300"
+
+type KeyHandler struct {
+	tokenService *service.TokenService
+}
+
+func NewKeyHandler(
+	tokenService *service.TokenService,
+) *KeyHandler {
+	return &KeyHandler{
+		tokenService: tokenService,
+	}
+}
+
+// JWKS serves a plain RFC 7517 key set.
+func (h *KeyHandler) JWKS(
+	ctx *fiber.Ctx,
+) error {
+	ctx.Set(
+		fiber.HeaderCacheControl,
+		"public, "+jwksMaxAge,
+	)
+
+	return ctx.JSON(
+		h.tokenService.JWKS(),
+	)
+}
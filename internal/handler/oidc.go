@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/service"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler(
+	oidcService *service.OIDCService,
+) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+func (h *OIDCHandler) Login(
+	ctx *fiber.Ctx,
+) error {
+	authURL, err := h.oidcService.Start(
+		ctx.UserContext(),
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to start single sign-on",
+				detail: fmt.Sprintf(
+					"oidc login; failed to start %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.Redirect(
+		authURL,
+		fiber.StatusFound,
+	)
+}
+
+// Callback answers like the password logins.
+func (h *OIDCHandler) Callback(
+	ctx *fiber.Ctx,
+) error {
+	var query model.OIDCCallbackQuery
+	err := ctx.QueryParser(&query)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid query",
+				detail: fmt.Sprintf(
+					"oidc callback; failed to parse query %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = query.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"oidc callback; invalid query: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.oidcService.Callback(
+		ctx.UserContext(),
+		query,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to login",
+				detail: fmt.Sprintf(
+					"oidc callback; failed to login %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
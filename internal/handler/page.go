@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+// queryLimit clamps the page size to util.MaxPageSize.
+func queryLimit(ctx *fiber.Ctx) int {
+	return util.PageSize(
+		ctx.QueryInt(
+			"limit",
+			util.DefaultPageSize,
+		),
+	)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/service"
+	"github.com/nozzlium/halosuster/internal/util"
 )
 
 type PatientHandler struct {
@@ -57,7 +58,7 @@ func (h *PatientHandler) Create(
 	}
 
 	patientData, err := h.patientService.Create(
-		ctx.Context(),
+		ctx.UserContext(),
 		patientModel,
 	)
 	if err != nil {
@@ -92,13 +93,31 @@ func (h *PatientHandler) FindAll(
 		"offset",
 		0,
 	)
-	queries.Limit = ctx.QueryInt(
-		"limit",
-		5,
+	queries.Limit = queryLimit(ctx)
+
+	var err error
+	queries.List, err = model.ParseListQuery(
+		ctx.Query("sort"),
+		ctx.Queries(),
+		model.PatientQueryFields,
+		model.PatientKeyset,
 	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find patients; invalid list query: %v",
+					err,
+				),
+			},
+		)
+	}
 
-	data, err := h.patientService.FindAll(
-		ctx.Context(),
+	data, meta, err := h.patientService.FindAll(
+		ctx.UserContext(),
 		queries,
 	)
 	if err != nil {
@@ -115,6 +134,299 @@ func (h *PatientHandler) FindAll(
 		)
 	}
 
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+		"meta":    meta,
+	})
+}
+
+func (h *PatientHandler) Update(
+	ctx *fiber.Ctx,
+) error {
+	identityNumber := ctx.Params("identityNumber")
+	err := util.ValidateIdentityNumber(
+		identityNumber,
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "patient not found",
+				detail: fmt.Sprintf(
+					"patient edit; invalid identity number %v",
+					err,
+				),
+			},
+		)
+	}
+
+	var body model.PatientEditBody
+	err = ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"patient edit; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	patientModel, err := body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"patient edit; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+	patientModel.IdentityNumber = identityNumber
+
+	data, err := h.patientService.Update(
+		ctx.UserContext(),
+		patientModel,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to edit",
+				detail: fmt.Sprintf(
+					"patient edit; failed to edit %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
+
+func (h *PatientHandler) Delete(
+	ctx *fiber.Ctx,
+) error {
+	identityNumber := ctx.Params("identityNumber")
+	err := util.ValidateIdentityNumber(
+		identityNumber,
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "patient not found",
+				detail: fmt.Sprintf(
+					"patient delete; invalid identity number %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.patientService.Delete(
+		ctx.UserContext(),
+		identityNumber,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to delete",
+				detail: fmt.Sprintf(
+					"patient delete; failed to delete %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *PatientHandler) Restore(
+	ctx *fiber.Ctx,
+) error {
+	identityNumber := ctx.Params("identityNumber")
+	err := util.ValidateIdentityNumber(
+		identityNumber,
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "patient not found",
+				detail: fmt.Sprintf(
+					"patient restore; invalid identity number %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.patientService.Restore(
+		ctx.UserContext(),
+		identityNumber,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to restore",
+				detail: fmt.Sprintf(
+					"patient restore; failed to restore %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *PatientHandler) FindDetail(
+	ctx *fiber.Ctx,
+) error {
+	identityNumber := ctx.Params("identityNumber")
+	err := util.ValidateIdentityNumber(
+		identityNumber,
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "patient not found",
+				detail: fmt.Sprintf(
+					"patient detail; invalid identity number %v",
+					err,
+				),
+			},
+		)
+	}
+
+	queries := model.RecordQuery{
+		IdentityNumber: identityNumber,
+		Offset: ctx.QueryInt(
+			"offset",
+			0,
+		),
+		Limit: queryLimit(ctx),
+	}
+
+	// records always come oldest first
+	queries.List, err = model.ParseListQuery(
+		"",
+		ctx.Queries(),
+		model.RecordQueryFields,
+		model.RecordKeyset,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"patient detail; invalid list query: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, meta, err := h.patientService.FindDetail(
+		ctx.UserContext(),
+		queries,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"patient detail; error finding patient: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+		"meta":    meta,
+	})
+}
+
+func (h *PatientHandler) Reveal(
+	ctx *fiber.Ctx,
+) error {
+	identityNumber := ctx.Params("identityNumber")
+	err := util.ValidateIdentityNumber(
+		identityNumber,
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "patient not found",
+				detail: fmt.Sprintf(
+					"patient reveal; invalid identity number %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.patientService.Reveal(
+		ctx.UserContext(),
+		identityNumber,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"patient reveal; error revealing patient: %v",
+					err,
+				),
+			},
+		)
+	}
+
 	return ctx.JSON(fiber.Map{
 		"message": "success",
 		"data":    data,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/service"
@@ -57,7 +58,7 @@ func (h *RecordHandler) Create(
 	}
 
 	_, err = h.recordService.Create(
-		ctx.Context(),
+		ctx.UserContext(),
 		recordModel,
 	)
 	if err != nil {
@@ -79,3 +80,207 @@ func (h *RecordHandler) Create(
 			"message": "success",
 		})
 }
+
+func (h *RecordHandler) FindAll(
+	ctx *fiber.Ctx,
+) error {
+	queries := model.RecordQuery{
+		IdentityNumber: ctx.Query(
+			"identityDetail.identityNumber",
+		),
+		UserID: ctx.Query(
+			"createdBy.userId",
+		),
+		NIP: ctx.Query(
+			"createdBy.nip",
+		),
+		CreatedAt: ctx.Query(
+			"createdAt",
+		),
+		Offset: ctx.QueryInt(
+			"offset",
+			0,
+		),
+		Limit: queryLimit(ctx),
+	}
+
+	var err error
+	queries.List, err = model.ParseListQuery(
+		ctx.Query("sort"),
+		ctx.Queries(),
+		model.RecordQueryFields,
+		model.RecordKeyset,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find records; invalid list query: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	// an invalid user ID can never match a record
+	if queries.UserID != "" {
+		if _, err := uuid.Parse(queries.UserID); err != nil {
+			return ctx.JSON(fiber.Map{
+				"message": "success",
+				"data":    []model.RecordResponseBody{},
+				"meta":    model.PageMeta{},
+			})
+		}
+	}
+
+	data, meta, err := h.recordService.FindAll(
+		ctx.UserContext(),
+		queries,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find records; error finding records: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+		"meta":    meta,
+	})
+}
+
+func (h *RecordHandler) Amend(
+	ctx *fiber.Ctx,
+) error {
+	recordID, err := uuid.Parse(
+		ctx.Params("id"),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "record not found",
+				detail: fmt.Sprintf(
+					"record amend; failed to parse record ID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	var body model.RecordAmendBody
+	err = ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"record amend; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	recordModel, err := body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"record amend; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+	recordModel.RecordID = recordID
+
+	data, err := h.recordService.Amend(
+		ctx.UserContext(),
+		recordModel,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to amend record",
+				detail: fmt.Sprintf(
+					"record amend; failed to amend record %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.Status(fiber.StatusCreated).
+		JSON(fiber.Map{
+			"message": "success",
+			"data":    data,
+		})
+}
+
+func (h *RecordHandler) FindRevisions(
+	ctx *fiber.Ctx,
+) error {
+	recordID, err := uuid.Parse(
+		ctx.Params("id"),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "record not found",
+				detail: fmt.Sprintf(
+					"record revisions; failed to parse record ID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.recordService.FindRevisions(
+		ctx.UserContext(),
+		recordID,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"record revisions; error finding revisions: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/service"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(
+	twoFactorService *service.TwoFactorService,
+) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+func (h *TwoFactorHandler) Enroll(
+	ctx *fiber.Ctx,
+) error {
+	data, err := h.twoFactorService.Enroll(
+		ctx.UserContext(),
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to enroll",
+				detail: fmt.Sprintf(
+					"2fa enroll; failed to enroll %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
+
+func (h *TwoFactorHandler) Verify(
+	ctx *fiber.Ctx,
+) error {
+	var body model.TwoFactorVerifyBody
+	err := ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"2fa verify; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"2fa verify; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.twoFactorService.Verify(
+		ctx.UserContext(),
+		body.Code,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to verify",
+				detail: fmt.Sprintf(
+					"2fa verify; failed to verify %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
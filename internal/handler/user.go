@@ -141,6 +141,65 @@ func (h *UserHandler) Login(
 	})
 }
 
+func (h *UserHandler) LoginTwoFactor(
+	ctx *fiber.Ctx,
+) error {
+	var body model.TwoFactorLoginBody
+	err := ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"user login 2fa; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"user login 2fa; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.userService.LoginTwoFactor(
+		ctx.UserContext(),
+		body,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to login",
+				detail: fmt.Sprintf(
+					"user login 2fa; failed to login %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
+
 func (h *UserHandler) RegisterNurse(
 	ctx *fiber.Ctx,
 ) error {
@@ -177,7 +236,7 @@ func (h *UserHandler) RegisterNurse(
 	}
 
 	data, err := h.userService.RegisterNurse(
-		ctx.Context(),
+		ctx.UserContext(),
 		userModel,
 	)
 	if err != nil {
@@ -299,8 +358,8 @@ func (h *UserHandler) GrantNurseAccess(
 		)
 	}
 
-	if !body.IsValid() {
-		err = constant.ErrBadInput
+	err = body.IsValid()
+	if err != nil {
 		return HandleError(
 			ctx,
 			ErrorResponse{
@@ -315,7 +374,7 @@ func (h *UserHandler) GrantNurseAccess(
 	}
 
 	err = h.userService.GrantNurseAccess(
-		ctx.Context(),
+		ctx.UserContext(),
 		model.User{
 			ID:       userId,
 			Password: body.Password,
@@ -340,6 +399,52 @@ func (h *UserHandler) GrantNurseAccess(
 	})
 }
 
+func (h *UserHandler) RevokeNurseAccess(
+	ctx *fiber.Ctx,
+) error {
+	userIdString := ctx.Params("userId")
+
+	userId, err := uuid.ParseBytes(
+		[]byte(userIdString),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "error parsing user ID",
+				detail: fmt.Sprintf(
+					"nurse revoke access; failed to parse userID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.userService.RevokeNurseAccess(
+		ctx.UserContext(),
+		userId,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to revoke access",
+				detail: fmt.Sprintf(
+					"nurse revoke access; failed to revoke access %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
 func (h *UserHandler) FindAll(
 	ctx *fiber.Ctx,
 ) error {
@@ -349,13 +454,31 @@ func (h *UserHandler) FindAll(
 		"offset",
 		0,
 	)
-	queries.Limit = ctx.QueryInt(
-		"limit",
-		5,
+	queries.Limit = queryLimit(ctx)
+
+	var err error
+	queries.List, err = model.ParseListQuery(
+		ctx.Query("sort"),
+		ctx.Queries(),
+		model.UserQueryFields,
+		model.UserKeyset,
 	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: err.Error(),
+				detail: fmt.Sprintf(
+					"find users; invalid list query: %v",
+					err,
+				),
+			},
+		)
+	}
 
-	data, err := h.userService.FindAll(
-		ctx.Context(),
+	data, meta, err := h.userService.FindAll(
+		ctx.UserContext(),
 		queries,
 	)
 	if err != nil {
@@ -375,6 +498,7 @@ func (h *UserHandler) FindAll(
 	return ctx.JSON(fiber.Map{
 		"message": "success",
 		"data":    data,
+		"meta":    meta,
 	})
 }
 
@@ -433,7 +557,7 @@ func (h *UserHandler) Update(
 	user.ID = userId
 
 	_, err = h.userService.UpdateNurse(
-		ctx.Context(),
+		ctx.UserContext(),
 		user,
 	)
 	if err != nil {
@@ -478,7 +602,7 @@ func (h *UserHandler) Delete(
 	}
 
 	_, err = h.userService.DeleteNurse(
-		ctx.Context(),
+		ctx.UserContext(),
 		userId,
 	)
 	if err != nil {
@@ -499,3 +623,322 @@ func (h *UserHandler) Delete(
 		fiber.Map{"message": "success"},
 	)
 }
+
+func (h *UserHandler) Restore(
+	ctx *fiber.Ctx,
+) error {
+	userIdString := ctx.Params("userId")
+
+	userId, err := uuid.ParseBytes(
+		[]byte(userIdString),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "error parsing user ID",
+				detail: fmt.Sprintf(
+					"nurse restore; failed to parse userID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.userService.RestoreNurse(
+		ctx.UserContext(),
+		userId,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to restore",
+				detail: fmt.Sprintf(
+					"nurse restore; failed to restore %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *UserHandler) Purge(
+	ctx *fiber.Ctx,
+) error {
+	userIdString := ctx.Params("userId")
+
+	userId, err := uuid.ParseBytes(
+		[]byte(userIdString),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "error parsing user ID",
+				detail: fmt.Sprintf(
+					"nurse purge; failed to parse userID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	var body model.NursePurgeRequestBody
+	err = ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"nurse purge; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	purge, err := body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"nurse purge; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.userService.PurgeNurse(
+		ctx.UserContext(),
+		userId,
+		purge,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to purge",
+				detail: fmt.Sprintf(
+					"nurse purge; failed to purge %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *UserHandler) RefreshToken(
+	ctx *fiber.Ctx,
+) error {
+	var body model.RefreshTokenRequestBody
+	err := ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"token refresh; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = body.IsValid()
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"token refresh; invalid body: %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.userService.RefreshToken(
+		ctx.UserContext(),
+		body.RefreshToken,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to refresh token",
+				detail: fmt.Sprintf(
+					"token refresh; failed to refresh token %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
+
+func (h *UserHandler) Logout(
+	ctx *fiber.Ctx,
+) error {
+	var body model.LogoutRequestBody
+	if len(ctx.Body()) > 0 {
+		err := ctx.BodyParser(&body)
+		if err != nil {
+			err = constant.ErrBadInput
+			return HandleError(
+				ctx,
+				ErrorResponse{
+					error:   err,
+					message: "invalid body",
+					detail: fmt.Sprintf(
+						"logout; failed to parse request body %v",
+						err,
+					),
+				},
+			)
+		}
+	}
+
+	err := h.userService.Logout(
+		ctx.UserContext(),
+		body.RefreshToken,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to logout",
+				detail: fmt.Sprintf(
+					"logout; failed to logout %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *UserHandler) Unlock(
+	ctx *fiber.Ctx,
+) error {
+	userIdString := ctx.Params("userId")
+
+	userId, err := uuid.ParseBytes(
+		[]byte(userIdString),
+	)
+	if err != nil {
+		err = constant.ErrNotFound
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "error parsing user ID",
+				detail: fmt.Sprintf(
+					"nurse unlock; failed to parse userID %v",
+					err,
+				),
+			},
+		)
+	}
+
+	err = h.userService.UnlockNurse(
+		ctx.UserContext(),
+		userId,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to unlock",
+				detail: fmt.Sprintf(
+					"nurse unlock; failed to unlock %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(
+		fiber.Map{"message": "success"},
+	)
+}
+
+func (h *UserHandler) ChangePassword(
+	ctx *fiber.Ctx,
+) error {
+	var body model.PasswordChangeBody
+	err := ctx.BodyParser(&body)
+	if err != nil {
+		err = constant.ErrBadInput
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "invalid body",
+				detail: fmt.Sprintf(
+					"password change; failed to parse request body %v",
+					err,
+				),
+			},
+		)
+	}
+
+	data, err := h.userService.ChangePassword(
+		ctx.UserContext(),
+		body,
+	)
+	if err != nil {
+		return HandleError(
+			ctx,
+			ErrorResponse{
+				error:   err,
+				message: "failed to change password",
+				detail: fmt.Sprintf(
+					"password change; failed to change password %v",
+					err,
+				),
+			},
+		)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "success",
+		"data":    data,
+	})
+}
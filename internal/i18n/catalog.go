@@ -0,0 +1,95 @@
+package i18n
+
+import "github.com/nozzlium/halosuster/internal/constant"
+
+// Every code must exist in Default.
+var messages = map[Lang]map[string]string{
+	EN: {
+		CodeBadInput:        "invalid input",
+		CodeInvalidBody:     "invalid body",
+		CodeNotFound:        "not found",
+		CodeConflict:        "already exists",
+		CodeUnauthorized:    "unauthorized",
+		CodeMissingToken:    "missing or malformed token",
+		CodeInvalidToken:    "invalid or expired token",
+		CodeForbidden:       "forbidden",
+		CodeInternal:        "internal server error",
+		CodeAccountLocked:   "account is locked after too many failed logins, try again later",
+		CodeTooManyAttempts: "too many failed logins, try again later",
+
+		"validation." + constant.ValidationRequired:  "is required",
+		"validation." + constant.ValidationLength:    "must be between {min} and {max} characters",
+		"validation." + constant.ValidationFormat:    "must be a valid {format}",
+		"validation." + constant.ValidationOneOf:     "must be one of {values}",
+		"validation." + constant.ValidationFileSize:  "must not be larger than {max} bytes",
+		"validation." + constant.ValidationCharacter: "must contain at least one {class}",
+		"validation." + constant.ValidationReused:    "must differ from the current password",
+		"validation." + constant.ValidationUnknown:   "is not supported",
+
+		"format." + constant.FormatNIP:             "NIP",
+		"format." + constant.FormatITNIP:           "IT staff NIP",
+		"format." + constant.FormatIdentityNumber:  "16 digit identity number",
+		"format." + constant.FormatPhoneNumber:     "phone number starting with +62",
+		"format." + constant.FormatDate:            "ISO 8601 date",
+		"format." + constant.FormatTime:            "RFC 3339 time",
+		"format." + constant.FormatURL:             "URL",
+		"format." + constant.FormatUUID:            "UUID",
+		"format." + constant.FormatImage:           "JPEG or PNG image",
+		"format." + constant.FormatTOTPCode:        "6 digit authenticator code",
+		"format." + constant.FormatNumber:          "number",
+		"format." + constant.FormatSort:            "list of distinct fields",
+		"format." + constant.FormatFilter:          "field[operator] filter",
+		"format." + constant.FormatCursor:          "cursor from meta.nextCursor",
+		"format." + constant.FormatCurrentPassword: "current password",
+		"format." + constant.FormatPurgedNIP:       "NIP of the user being purged",
+
+		"class." + constant.CharacterUpper:  "uppercase letter",
+		"class." + constant.CharacterLower:  "lowercase letter",
+		"class." + constant.CharacterDigit:  "digit",
+		"class." + constant.CharacterSymbol: "symbol",
+	},
+	ID: {
+		CodeBadInput:        "masukan tidak valid",
+		CodeInvalidBody:     "isi permintaan tidak valid",
+		CodeNotFound:        "data tidak ditemukan",
+		CodeConflict:        "data sudah ada",
+		CodeUnauthorized:    "tidak terautentikasi",
+		CodeMissingToken:    "token tidak ada atau tidak sesuai format",
+		CodeInvalidToken:    "token tidak valid atau sudah kedaluwarsa",
+		CodeForbidden:       "akses ditolak",
+		CodeInternal:        "terjadi kesalahan pada server",
+		CodeAccountLocked:   "akun dikunci karena terlalu banyak percobaan masuk yang gagal, coba lagi nanti",
+		CodeTooManyAttempts: "terlalu banyak percobaan masuk yang gagal, coba lagi nanti",
+
+		"validation." + constant.ValidationRequired:  "wajib diisi",
+		"validation." + constant.ValidationLength:    "harus terdiri dari {min} sampai {max} karakter",
+		"validation." + constant.ValidationFormat:    "harus berupa {format} yang valid",
+		"validation." + constant.ValidationOneOf:     "harus salah satu dari {values}",
+		"validation." + constant.ValidationFileSize:  "tidak boleh lebih dari {max} byte",
+		"validation." + constant.ValidationCharacter: "harus mengandung setidaknya satu {class}",
+		"validation." + constant.ValidationReused:    "harus berbeda dari kata sandi saat ini",
+		"validation." + constant.ValidationUnknown:   "tidak didukung",
+
+		"format." + constant.FormatNIP:             "NIP",
+		"format." + constant.FormatITNIP:           "NIP staf IT",
+		"format." + constant.FormatIdentityNumber:  "NIK 16 digit",
+		"format." + constant.FormatPhoneNumber:     "nomor telepon berawalan +62",
+		"format." + constant.FormatDate:            "tanggal ISO 8601",
+		"format." + constant.FormatTime:            "waktu RFC 3339",
+		"format." + constant.FormatURL:             "URL",
+		"format." + constant.FormatUUID:            "UUID",
+		"format." + constant.FormatImage:           "gambar JPEG atau PNG",
+		"format." + constant.FormatTOTPCode:        "kode autentikator 6 digit",
+		"format." + constant.FormatNumber:          "angka",
+		"format." + constant.FormatSort:            "daftar kolom yang tidak berulang",
+		"format." + constant.FormatFilter:          "filter kolom[operator]",
+		"format." + constant.FormatCursor:          "kursor dari meta.nextCursor",
+		"format." + constant.FormatCurrentPassword: "kata sandi saat ini",
+		"format." + constant.FormatPurgedNIP:       "NIP pengguna yang dihapus permanen",
+
+		"class." + constant.CharacterUpper:  "huruf besar",
+		"class." + constant.CharacterLower:  "huruf kecil",
+		"class." + constant.CharacterDigit:  "angka",
+		"class." + constant.CharacterSymbol: "simbol",
+	},
+}
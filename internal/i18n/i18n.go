@@ -0,0 +1,165 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+type Lang string
+
+const (
+	EN Lang = "en"
+	ID Lang = "id"
+)
+
+const Default = EN
+
+const (
+	CodeBadInput        = "bad_input"
+	CodeInvalidBody     = "invalid_body"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeUnauthorized    = "unauthorized"
+	CodeMissingToken    = "missing_token"
+	CodeInvalidToken    = "invalid_token"
+	CodeForbidden       = "forbidden"
+	CodeInternal        = "internal"
+	CodeAccountLocked   = "account_locked"
+	CodeTooManyAttempts = "too_many_attempts"
+)
+
+// Code falls back to CodeInternal.
+func Code(err error) string {
+	switch {
+	case errors.Is(err, constant.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, constant.ErrConflict):
+		return CodeConflict
+	case errors.Is(err, constant.ErrUnauthorized):
+		return CodeUnauthorized
+	case errors.Is(err, constant.ErrInvalidBody):
+		return CodeInvalidBody
+	case errors.Is(err, constant.ErrAccountLocked):
+		return CodeAccountLocked
+	case errors.Is(err, constant.ErrTooManyAttempts):
+		return CodeTooManyAttempts
+	case errors.Is(err, constant.ErrBadInput),
+		errors.Is(err, constant.ErrInsufficientFund),
+		errors.Is(err, constant.ErrInvalidChange),
+		errors.Is(err, constant.ErrInsufficientStock):
+		return CodeBadInput
+	default:
+		return CodeInternal
+	}
+}
+
+// Negotiate ignores region subtags, so id-ID is served Indonesian.
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		lang    Lang
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(
+			strings.TrimSpace(part),
+			";",
+		)
+		quality := 1.0
+		if q, ok := strings.CutPrefix(
+			strings.TrimSpace(params),
+			"q=",
+		); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(tag, "-")
+		lang := Lang(strings.ToLower(primary))
+		if _, ok := messages[lang]; !ok {
+			continue
+		}
+
+		candidates = append(
+			candidates,
+			candidate{
+				lang:    lang,
+				quality: quality,
+			},
+		)
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	return candidates[0].lang
+}
+
+// Message falls back to the default language and then to the code itself.
+func Message(
+	lang Lang,
+	code string,
+) string {
+	if message, ok := messages[lang][code]; ok {
+		return message
+	}
+	if message, ok := messages[Default][code]; ok {
+		return message
+	}
+
+	return code
+}
+
+// translatedParams are params whose values are catalog keys under a prefix.
+var translatedParams = map[string]string{
+	"format": "format.",
+	"class":  "class.",
+}
+
+// FieldMessage fills the `{name}` placeholders from params.
+func FieldMessage(
+	lang Lang,
+	code string,
+	params map[string]interface{},
+) string {
+	message := Message(
+		lang,
+		"validation."+code,
+	)
+	for name, value := range params {
+		var text string
+		switch value := value.(type) {
+		case []string:
+			text = strings.Join(value, ", ")
+		default:
+			text = fmt.Sprint(value)
+		}
+		if prefix, ok := translatedParams[name]; ok {
+			text = Message(lang, prefix+text)
+		}
+
+		message = strings.ReplaceAll(
+			message,
+			"{"+name+"}",
+			text,
+		)
+	}
+
+	return message
+}
@@ -0,0 +1,251 @@
+// Package jwtkey holds the signing keys, picked by the kid header.
+package jwtkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+var ErrUnknownKey = errors.New(
+	"unknown JWT signing key",
+)
+
+// legacyKeyID is the key of HS256 tokens without a kid
+const legacyKeyID = ""
+
+const minRSABits = 2048
+
+type key struct {
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+type Keyring struct {
+	keys   map[string]key
+	ids    []string
+	active string
+}
+
+// NewKeyring signs with the first of JWT_KEYS by default, or JWT_SECRET without any.
+func NewKeyring(cfg config.JWTConfig) (*Keyring, error) {
+	keyring := &Keyring{
+		keys: make(map[string]key),
+	}
+
+	for _, entry := range strings.Split(cfg.JWTKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, path, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf(
+				"JWT_KEYS: %q is not kid:path",
+				entry,
+			)
+		}
+		if _, exists := keyring.keys[id]; exists {
+			return nil, fmt.Errorf(
+				"JWT_KEYS: kid %q is listed twice",
+				id,
+			)
+		}
+		loaded, err := loadKey(path)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"JWT_KEYS: kid %q: %w",
+				id,
+				err,
+			)
+		}
+
+		keyring.keys[id] = loaded
+		keyring.ids = append(keyring.ids, id)
+	}
+
+	if cfg.JWTSecret != "" {
+		keyring.keys[legacyKeyID] = key{
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.JWTSecret),
+			public:  []byte(cfg.JWTSecret),
+		}
+	}
+	if len(keyring.keys) == 0 {
+		return nil, errors.New("neither JWT_KEYS nor JWT_SECRET is set")
+	}
+
+	keyring.active = legacyKeyID
+	if len(keyring.ids) > 0 {
+		keyring.active = keyring.ids[0]
+	}
+	if cfg.JWTActiveKeyID != "" {
+		if _, ok := keyring.keys[cfg.JWTActiveKeyID]; !ok {
+			return nil, fmt.Errorf(
+				"JWT_ACTIVE_KEY_ID %q is not in JWT_KEYS",
+				cfg.JWTActiveKeyID,
+			)
+		}
+		keyring.active = cfg.JWTActiveKeyID
+	}
+
+	return keyring, nil
+}
+
+func loadKey(path string) (key, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return key{}, err
+	}
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return key{}, errors.New("no PEM block found")
+	}
+
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return key{}, fmt.Errorf(
+			"unsupported PEM block %q",
+			block.Type,
+		)
+	}
+	if err != nil {
+		return key{}, err
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSABits {
+			return key{}, fmt.Errorf(
+				"RSA keys must have at least %d bits",
+				minRSABits,
+			)
+		}
+		return key{
+			method:  jwt.SigningMethodRS256,
+			private: private,
+			public:  &private.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		return key{
+			method:  jwt.SigningMethodEdDSA,
+			private: private,
+			public:  private.Public(),
+		}, nil
+	default:
+		return key{}, fmt.Errorf(
+			"unsupported key type %T, use RSA or Ed25519",
+			private,
+		)
+	}
+}
+
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	active := k.keys[k.active]
+	token := jwt.NewWithClaims(
+		active.method,
+		claims,
+	)
+	if k.active != legacyKeyID {
+		token.Header["kid"] = k.active
+	}
+
+	return token.SignedString(active.private)
+}
+
+// Keyfunc refuses tokens whose algorithm does not match their kid.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	found, ok := k.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != found.method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+
+	return found.public, nil
+}
+
+func (k *Keyring) Methods() []string {
+	methods := make([]string, 0, len(k.keys))
+	for _, found := range k.keys {
+		alg := found.method.Alg()
+		if !slices.Contains(methods, alg) {
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are the modulus and exponent of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are the curve and public key of Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS never publishes JWT_SECRET.
+func (k *Keyring) JWKS() JWKS {
+	jwks := JWKS{
+		Keys: make([]JWK, 0, len(k.ids)),
+	}
+	for _, id := range k.ids {
+		found := k.keys[id]
+		jwk := JWK{
+			KeyID:     id,
+			Use:       "sig",
+			Algorithm: found.method.Alg(),
+		}
+		switch public := found.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encode(public.N.Bytes())
+			jwk.E = encode(
+				big.NewInt(int64(public.E)).Bytes(),
+			)
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = encode(public)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,290 @@
+package jwtkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+// writeKey stores private as a PKCS #8 PEM file and returns its path.
+func writeKey(
+	t *testing.T,
+	name string,
+	private any,
+) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name+".pem")
+	err = os.WriteFile(
+		path,
+		pem.EncodeToMemory(&pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: der,
+		}),
+		0o600,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeEd25519Key(t *testing.T, name string) string {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writeKey(t, name, private)
+}
+
+func parse(
+	keyring *Keyring,
+	signed string,
+) error {
+	_, err := jwt.Parse(
+		signed,
+		keyring.Keyfunc,
+		jwt.WithValidMethods(keyring.Methods()),
+	)
+	return err
+}
+
+func TestSignAndVerify(t *testing.T) {
+	keyring, err := NewKeyring(
+		config.JWTConfig{
+			JWTKeys: "a:" + writeEd25519Key(t, "a") +
+				",b:" + writeEd25519Key(t, "b"),
+			JWTActiveKeyID: "b",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := keyring.Sign(jwt.MapClaims{"sub": "nurse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(
+		signed,
+		jwt.MapClaims{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "b" || token.Method.Alg() != "EdDSA" {
+		t.Errorf("header = %v, want kid b and EdDSA", token.Header)
+	}
+
+	err = parse(keyring, signed)
+	if err != nil {
+		t.Errorf("token did not verify: %v", err)
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	edPath := writeEd25519Key(t, "ed")
+	keyring, err := NewKeyring(
+		config.JWTConfig{
+			JWTKeys:   "ed:" + edPath,
+			JWTSecret: "legacy-secret",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a key that is not in the keyring, under a kid that is
+	_, stranger, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		key    any
+	}{
+		{
+			name:   "unknown kid",
+			method: jwt.SigningMethodEdDSA,
+			kid:    "other",
+			key:    stranger,
+		},
+		{
+			name:   "signed by another key",
+			method: jwt.SigningMethodEdDSA,
+			kid:    "ed",
+			key:    stranger,
+		},
+		{
+			// the Ed25519 public key must never act as an HMAC secret
+			name:   "algorithm does not match the kid",
+			method: jwt.SigningMethodHS256,
+			kid:    "ed",
+			key:    []byte("legacy-secret"),
+		},
+		{
+			name:   "unsigned",
+			method: jwt.SigningMethodNone,
+			kid:    "ed",
+			key:    jwt.UnsafeAllowNoneSignatureType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(
+				test.method,
+				jwt.MapClaims{"sub": "nurse"},
+			)
+			token.Header["kid"] = test.kid
+			signed, err := token.SignedString(test.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = parse(keyring, signed)
+			if err == nil {
+				t.Error("token verified, want it rejected")
+			}
+		})
+	}
+}
+
+func TestLegacySecret(t *testing.T) {
+	keyring, err := NewKeyring(
+		config.JWTConfig{
+			JWTKeys:   "ed:" + writeEd25519Key(t, "ed"),
+			JWTSecret: "legacy-secret",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// issued before the switch, without a kid
+	signed, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		jwt.MapClaims{"sub": "nurse"},
+	).SignedString([]byte("legacy-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = parse(keyring, signed)
+	if err != nil {
+		t.Errorf("legacy token did not verify: %v", err)
+	}
+
+	jwks := keyring.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "ed" {
+		t.Errorf("JWKS = %+v, want only the ed key", jwks.Keys)
+	}
+	if jwks.Keys[0].KeyType != "OKP" || jwks.Keys[0].Curve != "Ed25519" {
+		t.Errorf("JWK = %+v, want an Ed25519 OKP key", jwks.Keys[0])
+	}
+}
+
+func TestSecretOnly(t *testing.T) {
+	keyring, err := NewKeyring(
+		config.JWTConfig{
+			JWTSecret: "development-secret",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := keyring.Sign(jwt.MapClaims{"sub": "nurse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = parse(keyring, signed)
+	if err != nil {
+		t.Errorf("token did not verify: %v", err)
+	}
+	if len(keyring.JWKS().Keys) != 0 {
+		t.Error("JWKS publishes the secret")
+	}
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPath := writeEd25519Key(t, "ed")
+
+	tests := []struct {
+		name string
+		cfg  config.JWTConfig
+		want error
+	}{
+		{
+			name: "nothing configured",
+			cfg:  config.JWTConfig{},
+		},
+		{
+			name: "not kid:path",
+			cfg: config.JWTConfig{
+				JWTKeys: edPath,
+			},
+		},
+		{
+			name: "kid listed twice",
+			cfg: config.JWTConfig{
+				JWTKeys: "ed:" + edPath + ",ed:" + edPath,
+			},
+		},
+		{
+			name: "missing file",
+			cfg: config.JWTConfig{
+				JWTKeys: "ed:" + filepath.Join(t.TempDir(), "missing.pem"),
+			},
+			want: os.ErrNotExist,
+		},
+		{
+			name: "RSA key too small",
+			cfg: config.JWTConfig{
+				JWTKeys: "rsa:" + writeKey(t, "rsa", smallRSA),
+			},
+		},
+		{
+			name: "active key not listed",
+			cfg: config.JWTConfig{
+				JWTKeys:        "ed:" + edPath,
+				JWTActiveKeyID: "other",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewKeyring(test.cfg)
+			if err == nil {
+				t.Fatal("NewKeyring succeeded, want an error")
+			}
+			if test.want != nil && !errors.Is(err, test.want) {
+				t.Errorf("NewKeyring = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
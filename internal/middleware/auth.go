@@ -1,24 +1,36 @@
 package middleware
 
 import (
-	"os"
-
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/segmentio/asm/base64"
+	"github.com/nozzlium/halosuster/internal/auth"
+	"github.com/nozzlium/halosuster/internal/i18n"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/service"
 )
 
 // Protected protect routes
-func Protected() func(*fiber.Ctx) error {
-	secret := os.Getenv("JWT_SECRET")
-
+func Protected(
+	tokenService *service.TokenService,
+) func(*fiber.Ctx) error {
 	return jwtware.New(jwtware.Config{
-		SigningKey: jwtware.SigningKey{
-			Key: []byte(secret),
-		},
+		KeyFunc:      tokenService.Keyfunc,
 		ErrorHandler: jwtError,
 		ContextKey:   "userData",
+		// rejects tokens of deleted users and revoked sessions
+		SuccessHandler: func(c *fiber.Ctx) error {
+			claims := c.Locals("userData").(*jwt.Token).Claims.(jwt.MapClaims)
+			err := tokenService.ValidateClaims(
+				c.UserContext(),
+				claims,
+			)
+			if err != nil {
+				return jwtError(c, err)
+			}
+
+			return c.Next()
+		},
 	})
 }
 
@@ -27,48 +39,117 @@ func SetClaimsData() func(*fiber.Ctx) error {
 		user := c.Locals("userData").(*jwt.Token).Claims.(jwt.MapClaims)
 		employeeId, ok := user["ut"].(string)
 		if !ok {
-			return c.Status(fiber.StatusUnauthorized).
-				JSON(fiber.Map{"message": "invalid token"})
+			return abort(
+				c,
+				fiber.StatusUnauthorized,
+				i18n.CodeInvalidToken,
+			)
 		}
-		c.Locals(
-			"employeeId",
-			employeeId,
-		)
 
-		userIDByte, err := base64.RawStdEncoding.DecodeString(
-			user["si"].(string),
-		)
+		session, err := service.SessionFromClaims(user)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).
-				JSON(fiber.Map{"message": "invalid token"})
+			return abort(
+				c,
+				fiber.StatusUnauthorized,
+				i18n.CodeInvalidToken,
+			)
 		}
-		c.Locals(
-			"userID",
-			string(userIDByte),
+
+		roleClaims, _ := user["rl"].([]interface{})
+		roles := make(
+			[]string,
+			0,
+			len(roleClaims),
+		)
+		for _, roleClaim := range roleClaims {
+			if role, ok := roleClaim.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+
+		c.SetUserContext(
+			auth.WithPrincipal(
+				c.UserContext(),
+				auth.Principal{
+					UserID:       session.UserID,
+					NIP:          employeeId,
+					Roles:        roles,
+					TokenID:      session.TokenID,
+					TokenVersion: session.TokenVersion,
+					ExpiresAt:    session.ExpiresAt,
+				},
+			),
 		)
 
 		return c.Next()
 	}
 }
 
+// RequirePermission must run after SetClaimsData.
+func RequirePermission(
+	permissions model.PermissionSet,
+	permission string,
+) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		principal, err := auth.PrincipalFrom(
+			c.UserContext(),
+		)
+		if err != nil ||
+			!permissions.Allows(
+				principal.Roles,
+				permission,
+			) {
+			return abort(
+				c,
+				fiber.StatusForbidden,
+				i18n.CodeForbidden,
+			)
+		}
+
+		return c.Next()
+	}
+}
+
 func jwtError(
 	c *fiber.Ctx,
 	err error,
 ) error {
+	code := i18n.CodeInvalidToken
 	if err.Error() == "Missing or malformed JWT" {
-		c.Status(
-			fiber.StatusUnauthorized,
-		)
-		return c.JSON(
-			fiber.Map{
-				"status":  "error",
-				"message": "Missing or malformed JWT",
-				"data":    nil,
-			},
-		)
-
-	} else {
-		c.Status(fiber.StatusUnauthorized)
-		return c.JSON(fiber.Map{"status": "error", "message": "Invalid or expired JWT", "data": nil})
+		code = i18n.CodeMissingToken
 	}
+
+	lang := i18n.Negotiate(
+		c.Get(fiber.HeaderAcceptLanguage),
+	)
+	c.Set(
+		fiber.HeaderContentLanguage,
+		string(lang),
+	)
+	return c.Status(fiber.StatusUnauthorized).
+		JSON(fiber.Map{
+			"status":  "error",
+			"message": i18n.Message(lang, code),
+			"code":    code,
+			"data":    nil,
+		})
+}
+
+func abort(
+	c *fiber.Ctx,
+	status int,
+	code string,
+) error {
+	lang := i18n.Negotiate(
+		c.Get(fiber.HeaderAcceptLanguage),
+	)
+	c.Set(
+		fiber.HeaderContentLanguage,
+		string(lang),
+	)
+	return c.Status(status).
+		JSON(fiber.Map{
+			"message": i18n.Message(lang, code),
+			"code":    code,
+		})
 }
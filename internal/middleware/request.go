@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
+)
+
+// RequestInfo reuses the caller's X-Request-ID when present.
+func RequestInfo() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if requestID == "" ||
+			len(requestID) > 64 {
+			requestID = uuid.NewString()
+		}
+		c.Set(
+			fiber.HeaderXRequestID,
+			requestID,
+		)
+		c.SetUserContext(
+			auth.WithRequest(
+				c.UserContext(),
+				auth.Request{
+					ID: requestID,
+					IP: c.IP(),
+				},
+			),
+		)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,296 @@
+// Package migration shares schema_migrations with golang-migrate.
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDirty = errors.New(
+	"database is in a dirty migration state",
+)
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration Migration
+	Applied   bool
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+// New loads every <version>_<name>.(up|down).sql file in dir of fsys.
+func New(
+	db *pgxpool.Pool,
+	fsys fs.FS,
+	dir string,
+) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		versionString, name, ok := strings.Cut(
+			strings.TrimSuffix(fileName, "."+direction+".sql"),
+			"_",
+		)
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+		version, err := strconv.ParseUint(versionString, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(
+			fsys,
+			path.Join(dir, fileName),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{
+				Version: version,
+				Name:    name,
+			}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make(
+		[]Migration,
+		0,
+		len(byVersion),
+	)
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+func (m *Migrator) Up(
+	ctx context.Context,
+) ([]Migration, error) {
+	current, err := m.currentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if migration.Version <= current {
+			continue
+		}
+
+		err := m.apply(
+			ctx,
+			migration.Up,
+			migration.Version,
+		)
+		if err != nil {
+			return applied, fmt.Errorf(
+				"applying %d_%s: %w",
+				migration.Version,
+				migration.Name,
+				err,
+			)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) Down(
+	ctx context.Context,
+	steps int,
+) ([]Migration, error) {
+	current, err := m.currentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := make([]Migration, 0, steps)
+	for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := m.migrations[i]
+		if migration.Version > current {
+			continue
+		}
+
+		var previous uint64
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		err := m.apply(
+			ctx,
+			migration.Down,
+			previous,
+		)
+		if err != nil {
+			return reverted, fmt.Errorf(
+				"reverting %d_%s: %w",
+				migration.Version,
+				migration.Name,
+				err,
+			)
+		}
+		reverted = append(reverted, migration)
+		current = previous
+	}
+
+	return reverted, nil
+}
+
+func (m *Migrator) Status(
+	ctx context.Context,
+) ([]Status, error) {
+	current, err := m.currentVersion(ctx)
+	if err != nil &&
+		!errors.Is(err, ErrDirty) {
+		return nil, err
+	}
+
+	statuses := make(
+		[]Status,
+		0,
+		len(m.migrations),
+	)
+	for _, migration := range m.migrations {
+		statuses = append(
+			statuses,
+			Status{
+				Migration: migration,
+				Applied:   migration.Version <= current,
+			},
+		)
+	}
+
+	return statuses, err
+}
+
+// apply records version in the same transaction as sql.
+func (m *Migrator) apply(
+	ctx context.Context,
+	sql string,
+	version uint64,
+) error {
+	tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if strings.TrimSpace(sql) != "" {
+		// the simple protocol accepts several statements at once
+		_, err = tx.Exec(ctx, sql)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		"truncate schema_migrations",
+	)
+	if err != nil {
+		return err
+	}
+	if version > 0 {
+		_, err = tx.Exec(
+			ctx,
+			"insert into schema_migrations (version, dirty) values ($1, false)",
+			int64(version),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) currentVersion(
+	ctx context.Context,
+) (uint64, error) {
+	_, err := m.db.Exec(
+		ctx,
+		`
+    create table if not exists schema_migrations (
+      version bigint not null primary key,
+      dirty boolean not null
+    )
+  `,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = m.db.QueryRow(
+		ctx,
+		"select version, dirty from schema_migrations limit 1",
+	).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if dirty {
+		return uint64(version), fmt.Errorf("%w at version %d", ErrDirty, version)
+	}
+
+	return uint64(version), nil
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+type AuditEvent struct {
+	ID         uuid.UUID
+	ActorID    uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	IP         string
+	CreatedAt  time.Time
+}
+
+type AuditEventResponseBody struct {
+	ID         string `json:"id"`
+	ActorID    string `json:"actorId"`
+	Action     string `json:"action"`
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	RequestID  string `json:"requestId"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+func (event *AuditEvent) ToResponseBody() AuditEventResponseBody {
+	var actorID string
+	if event.ActorID != uuid.Nil {
+		actorID = event.ActorID.String()
+	}
+
+	return AuditEventResponseBody{
+		ID:         event.ID.String(),
+		ActorID:    actorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		RequestID:  event.RequestID,
+		IP:         event.IP,
+		CreatedAt: util.ToISO8601(
+			event.CreatedAt,
+		),
+	}
+}
+
+var AuditQueryFields = util.QueryFields{
+	"action": {
+		Column: "action",
+		Type:   util.FieldString,
+		Ops:    util.EqualityOps,
+	},
+	"targetType": {
+		Column: "target_type",
+		Type:   util.FieldString,
+		Ops:    util.EqualityOps,
+	},
+	"createdAt": {
+		Column:   "created_at",
+		Type:     util.FieldTime,
+		Sortable: true,
+		Ops:      util.RangeOps,
+	},
+}
+
+var AuditKeyset = util.Keyset{
+	Field:          "createdAt",
+	Column:         "created_at",
+	Tiebreaker:     "id",
+	TiebreakerType: util.FieldUUID,
+}
+
+type AuditQuery struct {
+	ActorID    string         `query:"actorId"`
+	Action     string         `query:"action"`
+	TargetType string         `query:"targetType"`
+	TargetID   string         `query:"targetId"`
+	From       string         `query:"from"`
+	To         string         `query:"to"`
+	CreatedAt  OrderBy        `query:"createdAt"`
+	List       util.ListQuery `query:"-"`
+	Offset     int
+	Limit      int
+	from       time.Time
+	to         time.Time
+}
+
+func (q *AuditQuery) IsValid() error {
+	var validation ValidationError
+	if q.ActorID != "" {
+		if _, err := uuid.Parse(q.ActorID); err != nil {
+			validation.Format("actorId", constant.FormatUUID)
+		}
+	}
+
+	if q.From != "" {
+		from, err := time.Parse(
+			time.RFC3339,
+			q.From,
+		)
+		if err != nil {
+			validation.Format("from", constant.FormatTime)
+		}
+		q.from = from
+	}
+
+	if q.To != "" {
+		to, err := time.Parse(
+			time.RFC3339,
+			q.To,
+		)
+		if err != nil {
+			validation.Format("to", constant.FormatTime)
+		}
+		q.to = to
+	}
+
+	return validation.Err()
+}
+
+// BuildFilterClauses leaves out the cursor so the total can be counted.
+func (q *AuditQuery) BuildFilterClauses() ([]string, []interface{}) {
+	clauses := make([]string, 0, 6)
+	params := make([]interface{}, 0, 6)
+
+	if q.ActorID != "" {
+		clauses = append(
+			clauses,
+			"actor_id = $%d",
+		)
+		params = append(
+			params,
+			q.ActorID,
+		)
+	}
+
+	if q.Action != "" {
+		clauses = append(
+			clauses,
+			"action = $%d",
+		)
+		params = append(
+			params,
+			q.Action,
+		)
+	}
+
+	if q.TargetType != "" {
+		clauses = append(
+			clauses,
+			"target_type = $%d",
+		)
+		params = append(
+			params,
+			q.TargetType,
+		)
+	}
+
+	if q.TargetID != "" {
+		clauses = append(
+			clauses,
+			"target_id = $%d",
+		)
+		params = append(
+			params,
+			q.TargetID,
+		)
+	}
+
+	if !q.from.IsZero() {
+		clauses = append(
+			clauses,
+			"created_at >= $%d",
+		)
+		params = append(
+			params,
+			q.from,
+		)
+	}
+
+	if !q.to.IsZero() {
+		clauses = append(
+			clauses,
+			"created_at < $%d",
+		)
+		params = append(
+			params,
+			q.to,
+		)
+	}
+
+	listClauses, listParams := q.List.WhereClauses()
+	clauses = append(clauses, listClauses...)
+	params = append(params, listParams...)
+
+	return clauses, params
+}
+
+func (q *AuditQuery) BuildWhereClauses() ([]string, []interface{}) {
+	clauses, params := q.BuildFilterClauses()
+	cursorClause, cursorParams, ok := q.List.CursorClause(
+		AuditKeyset,
+		q.createdAtDesc(),
+	)
+	if ok {
+		clauses = append(clauses, cursorClause)
+		params = append(params, cursorParams...)
+	}
+
+	return clauses, params
+}
+
+func (q *AuditQuery) BuildPagination() (string, []interface{}) {
+	return q.List.Pagination(
+		q.Limit,
+		q.Offset,
+	)
+}
+
+func (q *AuditQuery) BuildOrderByClause() []string {
+	return q.List.OrderByClauses(
+		AuditKeyset,
+		q.createdAtDesc(),
+	)
+}
+
+// createdAtDesc honours the legacy createdAt=asc|desc.
+func (q *AuditQuery) createdAtDesc() bool {
+	return q.CreatedAt != Asc
+}
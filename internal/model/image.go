@@ -0,0 +1,61 @@
+package model
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+// imageExtensions is keyed by the sniffed content type, not the claimed one.
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+}
+
+type ImageUpload struct {
+	Size    int64
+	Content io.Reader
+}
+
+// IsValid returns the sniffed content type and extension of the upload.
+func (image *ImageUpload) IsValid(
+	maxSize int64,
+) (string, string, error) {
+	var validation ValidationError
+	if image.Size == 0 ||
+		image.Content == nil {
+		validation.Required("file")
+		return "", "", validation.Err()
+	}
+	if image.Size > maxSize {
+		validation.FileSize("file", maxSize)
+		return "", "", validation.Err()
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(image.Content, head)
+	if err != nil &&
+		err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	head = head[:n]
+	image.Content = io.MultiReader(
+		bytes.NewReader(head),
+		image.Content,
+	)
+
+	contentType := http.DetectContentType(head)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		validation.Format("file", constant.FormatImage)
+		return "", "", validation.Err()
+	}
+
+	return contentType, extension, nil
+}
+
+type ImageUploadResponseBody struct {
+	ImageURL string `json:"imageUrl"`
+}
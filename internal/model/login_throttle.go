@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type LoginThrottle struct {
+	Key            string
+	FailedAttempts int
+	Lockouts       int
+	LockedUntil    time.Time
+	UpdatedAt      time.Time
+}
+
+// LoginThrottleError wraps constant.ErrAccountLocked or constant.ErrTooManyAttempts.
+type LoginThrottleError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottleError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginThrottleError) Unwrap() error {
+	return e.Err
+}
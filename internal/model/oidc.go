@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+// OIDCState is a pending single sign-on, stored by the hash of its state.
+type OIDCState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+type UserIdentity struct {
+	Issuer    string
+	Subject   string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ExternalUser struct {
+	Issuer     string
+	Subject    string
+	EmployeeID string
+	Name       string
+}
+
+type OIDCCallbackQuery struct {
+	Code             string `query:"code"`
+	State            string `query:"state"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+func (q *OIDCCallbackQuery) IsValid() error {
+	if q.Error != "" {
+		return fmt.Errorf(
+			"%w: OpenID provider: %s: %s",
+			constant.ErrUnauthorized,
+			q.Error,
+			q.ErrorDescription,
+		)
+	}
+
+	var validation ValidationError
+	validation.CheckLength("code", q.Code, 1, 2048)
+	validation.CheckLength("state", q.State, 1, 100)
+
+	return validation.Err()
+}
@@ -0,0 +1,7 @@
+package model
+
+// PageMeta only fills Total when asked for with total=true.
+type PageMeta struct {
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
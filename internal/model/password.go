@@ -0,0 +1,69 @@
+package model
+
+import (
+	"unicode"
+
+	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+// passwordMaxLength is where bcrypt stops looking at the password.
+const passwordMaxLength = 72
+
+type PasswordChangeBody struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+// IsValid only checks the old password for presence.
+func (body *PasswordChangeBody) IsValid(
+	policy config.PasswordPolicyConfig,
+) error {
+	var validation ValidationError
+	if body.OldPassword == "" {
+		validation.Required("oldPassword")
+	}
+
+	validation.CheckLength(
+		"newPassword",
+		body.NewPassword,
+		policy.PasswordMinLength,
+		passwordMaxLength,
+	)
+
+	var upper, lower, digit, symbol bool
+	for _, c := range body.NewPassword {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			symbol = true
+		}
+	}
+	classes := []struct {
+		required bool
+		present  bool
+		class    string
+	}{
+		{policy.PasswordRequireUpper, upper, constant.CharacterUpper},
+		{policy.PasswordRequireLower, lower, constant.CharacterLower},
+		{policy.PasswordRequireDigit, digit, constant.CharacterDigit},
+		{policy.PasswordRequireSymbol, symbol, constant.CharacterSymbol},
+	}
+	for _, class := range classes {
+		if class.required && !class.present {
+			validation.Character("newPassword", class.class)
+		}
+	}
+
+	if body.NewPassword != "" &&
+		body.NewPassword == body.OldPassword {
+		validation.Reused("newPassword")
+	}
+
+	return validation.Err()
+}
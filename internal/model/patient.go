@@ -1,7 +1,6 @@
 package model
 
 import (
-	"fmt"
 	"strconv"
 	"time"
 
@@ -11,8 +10,11 @@ import (
 )
 
 type Patient struct {
-	IdentityNumber  string
+	IdentityNumber string
+	// IdentityIndex is the blind index patients are keyed by
+	IdentityIndex   string
 	UserID          uuid.UUID
+	UpdatedBy       uuid.UUID
 	PhoneNumber     string
 	Name            string
 	Birthdate       time.Time
@@ -33,59 +35,118 @@ type PatientRegisterBody struct {
 }
 
 func (body *PatientRegisterBody) IsValid() (Patient, error) {
-	var patient Patient
-	identityNumberString := strconv.FormatUint(
-		body.IdentityNumber,
-		10,
+	var (
+		patient    Patient
+		validation ValidationError
 	)
-	err := util.ValidateIdentityNumber(
-		identityNumberString,
+	patient.IdentityNumber = checkIdentityNumber(
+		&validation,
+		body.IdentityNumber,
 	)
-	if err != nil {
-		return patient, err
-	}
-	patient.IdentityNumber = identityNumberString
 
-	err = util.ValidatePhoneNumber(
+	patient.setProfile(
+		&validation,
 		body.PhoneNumber,
+		body.Name,
+		body.Birthdate,
+		body.Gender,
+		body.IdentityCardScanImg,
 	)
-	if err != nil {
-		return patient, err
-	}
-	patient.PhoneNumber = body.PhoneNumber
 
-	if nameLen := len(body.Name); nameLen < 3 ||
-		nameLen > 30 {
-		return patient, constant.ErrBadInput
-	}
-	patient.Name = body.Name
+	return patient, validation.Err()
+}
 
-	if body.Birthdate == "" {
-		return patient, constant.ErrBadInput
-	}
-	birthdate, err := time.Parse(
-		"2006-01-02T15:04:05.999Z",
+type PatientEditBody struct {
+	PhoneNumber         string `json:"phoneNumber"`
+	Name                string `json:"name"`
+	Birthdate           string `json:"birthdate"`
+	Gender              string `json:"gender"`
+	IdentityCardScanImg string `json:"identityCardScanImg"`
+}
+
+func (body *PatientEditBody) IsValid() (Patient, error) {
+	var (
+		patient    Patient
+		validation ValidationError
+	)
+	patient.setProfile(
+		&validation,
+		body.PhoneNumber,
+		body.Name,
 		body.Birthdate,
+		body.Gender,
+		body.IdentityCardScanImg,
 	)
-	if err != nil {
-		return patient, constant.ErrBadInput
+
+	return patient, validation.Err()
+}
+
+func (patient *Patient) setProfile(
+	validation *ValidationError,
+	phoneNumber string,
+	name string,
+	birthdateString string,
+	gender string,
+	identityCardScanImg string,
+) {
+	if phoneNumber == "" {
+		validation.Required("phoneNumber")
+	} else if util.ValidatePhoneNumber(
+		phoneNumber,
+	) != nil {
+		validation.Format("phoneNumber", constant.FormatPhoneNumber)
 	}
-	patient.Birthdate = birthdate
+	patient.PhoneNumber = phoneNumber
+
+	validation.CheckLength("name", name, 3, 30)
+	patient.Name = name
 
-	if body.Gender != "male" &&
-		body.Gender != "female" {
-		return patient, constant.ErrBadInput
+	if birthdateString == "" {
+		validation.Required("birthdate")
+	} else {
+		birthdate, err := time.Parse(
+			"2006-01-02T15:04:05.999Z",
+			birthdateString,
+		)
+		if err != nil {
+			validation.Format("birthdate", constant.FormatDate)
+		}
+		patient.Birthdate = birthdate
 	}
-	patient.Gender = body.Gender
 
-	if !util.ValidateURL(
-		body.IdentityCardScanImg,
-	) {
-		return patient, constant.ErrBadInput
+	if gender == "" {
+		validation.Required("gender")
+	} else if gender != "male" &&
+		gender != "female" {
+		validation.OneOf("gender", "male", "female")
 	}
-	patient.IdentityScanImg = body.IdentityCardScanImg
+	patient.Gender = gender
 
-	return patient, nil
+	checkURL(
+		validation,
+		"identityCardScanImg",
+		identityCardScanImg,
+	)
+	patient.IdentityScanImg = identityCardScanImg
+}
+
+func checkIdentityNumber(
+	validation *ValidationError,
+	identityNumber uint64,
+) string {
+	identityNumberString := strconv.FormatUint(
+		identityNumber,
+		10,
+	)
+	if identityNumber == 0 {
+		validation.Required("identityNumber")
+	} else if util.ValidateIdentityNumber(
+		identityNumberString,
+	) != nil {
+		validation.Format("identityNumber", constant.FormatIdentityNumber)
+	}
+
+	return identityNumberString
 }
 
 type PatientResponseBody struct {
@@ -94,6 +155,8 @@ type PatientResponseBody struct {
 	Name           string `json:"name"`
 	Birthdate      string `json:"birthDate"`
 	Gender         string `json:"gender"`
+	CreatedBy      string `json:"createdBy"`
+	UpdatedBy      string `json:"updatedBy"`
 	CreatedAt      string `json:"createdAt"`
 }
 
@@ -115,23 +178,95 @@ func (patient *Patient) ToResponseBody() (PatientResponseBody, error) {
 		Birthdate: util.ToISO8601(
 			patient.Birthdate,
 		),
-		Gender: patient.Gender,
+		Gender:    patient.Gender,
+		CreatedBy: userIDString(patient.UserID),
+		UpdatedBy: userIDString(patient.UpdatedBy),
 		CreatedAt: util.ToISO8601(
 			patient.CreatedAt,
 		),
 	}, nil
 }
 
+// PatientMaskedResponseBody masks the identity and phone numbers.
+type PatientMaskedResponseBody struct {
+	IdentityNumber string `json:"identityNumber"`
+	PhoneNumber    string `json:"phoneNumber"`
+	Name           string `json:"name"`
+	Birthdate      string `json:"birthDate"`
+	Gender         string `json:"gender"`
+	CreatedBy      string `json:"createdBy"`
+	UpdatedBy      string `json:"updatedBy"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+func (patient *Patient) ToMaskedResponseBody() PatientMaskedResponseBody {
+	return PatientMaskedResponseBody{
+		IdentityNumber: util.MaskIdentityNumber(
+			patient.IdentityNumber,
+		),
+		PhoneNumber: util.MaskPhoneNumber(
+			patient.PhoneNumber,
+		),
+		Name: patient.Name,
+		Birthdate: util.ToISO8601(
+			patient.Birthdate,
+		),
+		Gender:    patient.Gender,
+		CreatedBy: userIDString(patient.UserID),
+		UpdatedBy: userIDString(patient.UpdatedBy),
+		CreatedAt: util.ToISO8601(
+			patient.CreatedAt,
+		),
+	}
+}
+
+type PatientRevealResponseBody struct {
+	PatientResponseBody
+	IdentityCardScanImg string `json:"identityCardScanImg"`
+}
+
+type PatientDetailResponseBody struct {
+	PatientMaskedResponseBody
+	IdentityCardScanImg string                       `json:"identityCardScanImg"`
+	Records             []RecordRevisionResponseBody `json:"records"`
+}
+
+var PatientQueryFields = util.QueryFields{
+	"createdAt": {
+		Column:   "created_at",
+		Type:     util.FieldTime,
+		Sortable: true,
+		Ops:      util.RangeOps,
+	},
+}
+
+var PatientKeyset = util.Keyset{
+	Field:          "createdAt",
+	Column:         "created_at",
+	Tiebreaker:     "identity_number",
+	TiebreakerType: util.FieldString,
+}
+
+// PatientQueryIndex holds the blind indexes the encrypted columns are matched on.
+type PatientQueryIndex struct {
+	IdentityNumber    string
+	PhoneNumberPrefix string
+	NameTokens        []string
+}
+
 type PatientQuery struct {
-	IdentityNumber string `query:"identityNumber"`
-	Name           string `query:"name"`
-	PhoneNumber    string `query:"phoneNumber"`
-	CreatedAt      string `query:"createdAt"`
+	IdentityNumber string            `query:"identityNumber"`
+	Name           string            `query:"name"`
+	PhoneNumber    string            `query:"phoneNumber"`
+	CreatedAt      string            `query:"createdAt"`
+	List           util.ListQuery    `query:"-"`
+	Index          PatientQueryIndex `query:"-"`
 	Offset         int
 	Limit          int
 }
 
-func (q *PatientQuery) BuildWhereClauses() ([]string, []interface{}) {
+// BuildFilterClauses leaves out the cursor so the total can be counted.
+func (q *PatientQuery) BuildFilterClauses() ([]string, []interface{}) {
 	clauses := make([]string, 0, 4)
 	params := make([]interface{}, 0, 4)
 
@@ -142,73 +277,69 @@ func (q *PatientQuery) BuildWhereClauses() ([]string, []interface{}) {
 		)
 		params = append(
 			params,
-			q.IdentityNumber,
+			q.Index.IdentityNumber,
 		)
 	}
 
+	// "bud san" finds "Budi Santoso"
 	if q.Name != "" {
 		clauses = append(
 			clauses,
-			`name ilike '%%' || $%d || '%%'`,
+			"name_index @> $%d::text[]",
+		)
+		params = append(
+			params,
+			q.Index.NameTokens,
 		)
-		params = append(params, q.Name)
 	}
 
 	if q.PhoneNumber != "" {
-		phoneNumberString := "+" + q.PhoneNumber
 		clauses = append(
 			clauses,
-			`phone_number like $%d || '%%'`,
+			"phone_number_index @> array[$%d]::text[]",
 		)
 		params = append(
 			params,
-			phoneNumberString,
+			q.Index.PhoneNumberPrefix,
 		)
 	}
 
+	listClauses, listParams := q.List.WhereClauses()
+	clauses = append(clauses, listClauses...)
+	params = append(params, listParams...)
+
 	return clauses, params
 }
 
-func (q *PatientQuery) BuildPagination() (string, []interface{}) {
-	var params []interface{}
-
-	limit := 5
-	offset := 0
-	if q.Limit > 0 {
-		limit = q.Limit
-	}
-	if q.Offset > 0 {
-		offset = q.Offset
-	}
-	params = append(
-		params,
-		limit,
-		offset,
+func (q *PatientQuery) BuildWhereClauses() ([]string, []interface{}) {
+	clauses, params := q.BuildFilterClauses()
+	cursorClause, cursorParams, ok := q.List.CursorClause(
+		PatientKeyset,
+		q.createdAtDesc(),
 	)
+	if ok {
+		clauses = append(clauses, cursorClause)
+		params = append(params, cursorParams...)
+	}
 
-	return "limit $%d offset $%d", params
+	return clauses, params
+}
+
+func (q *PatientQuery) BuildPagination() (string, []interface{}) {
+	return q.List.Pagination(
+		q.Limit,
+		q.Offset,
+	)
 }
 
 func (q *PatientQuery) BuildOrderByClause() []string {
-	var sqlClause []string
-
-	if q.CreatedAt != "" ||
-		OrderBy(
-			q.CreatedAt,
-		).IsValid() {
-		sqlClause = append(
-			sqlClause,
-			fmt.Sprintf(
-				"created_at %s",
-				q.CreatedAt,
-			),
-		)
-	} else {
-		sqlClause = append(
-			sqlClause,
-			"created_at desc",
-		)
-	}
+	return q.List.OrderByClauses(
+		PatientKeyset,
+		q.createdAtDesc(),
+	)
+}
 
-	return sqlClause
+// createdAtDesc honours the legacy createdAt=asc|desc.
+func (q *PatientQuery) createdAtDesc() bool {
+	return OrderBy(q.CreatedAt) != Asc
 }
@@ -5,19 +5,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/util"
 )
 
 type Record struct {
 	ID             uuid.UUID
+	RecordID       uuid.UUID
+	Revision       int
+	AmendReason    string
 	IdentityNumber string
 	UserID         uuid.UUID
+	CreatedBy      uuid.UUID
 	Symptomps      string
 	Medications    string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	DeletedAt      time.Time
+	SupersededAt   time.Time
+	Patient        Patient
+	Creator        User
+	User           User
 }
 
 type RecordRegisterBody struct {
@@ -27,36 +34,26 @@ type RecordRegisterBody struct {
 }
 
 func (body *RecordRegisterBody) IsValid() (Record, error) {
-	var record Record
-	identityNumberString := strconv.FormatUint(
-		body.IdentityNumber,
-		10,
+	var (
+		record     Record
+		validation ValidationError
 	)
-	err := util.ValidateIdentityNumber(
-		identityNumberString,
+	record.IdentityNumber = checkIdentityNumber(
+		&validation,
+		body.IdentityNumber,
 	)
-	if err != nil {
-		return record, err
-	}
-	record.IdentityNumber = identityNumberString
 
-	if sympLen := len(body.Symptomps); sympLen < 1 ||
-		sympLen > 2000 {
-		return record, constant.ErrBadInput
-	}
+	validation.CheckLength("symptoms", body.Symptomps, 1, 2000)
 	record.Symptomps = body.Symptomps
 
-	if medLen := len(body.Medications); medLen < 1 ||
-		medLen > 2000 {
-		return record, constant.ErrBadInput
-	}
+	validation.CheckLength("medications", body.Medications, 1, 2000)
 	record.Medications = body.Medications
 
-	return record, nil
+	return record, validation.Err()
 }
 
 type RecordPatientBody struct {
-	IdentityNumber      uint64 `json:"identityNumber"`
+	IdentityNumber      string `json:"identityNumber"`
 	PhoneNumber         string `json:"phoneNumber"`
 	Name                string `json:"name"`
 	Birthdate           string `json:"birthdate"`
@@ -70,10 +67,229 @@ type RecordUserBody struct {
 	UserID string `json:"userId"`
 }
 
+type RecordAmendBody struct {
+	Symptomps   string `json:"symptoms"`
+	Medications string `json:"medications"`
+	Reason      string `json:"reason"`
+}
+
+func (body *RecordAmendBody) IsValid() (Record, error) {
+	var (
+		record     Record
+		validation ValidationError
+	)
+	validation.CheckLength("symptoms", body.Symptomps, 1, 2000)
+	record.Symptomps = body.Symptomps
+
+	validation.CheckLength("medications", body.Medications, 1, 2000)
+	record.Medications = body.Medications
+
+	validation.CheckLength("reason", body.Reason, 1, 500)
+	record.AmendReason = body.Reason
+
+	return record, validation.Err()
+}
+
 type RecordResponseBody struct {
+	RecordID       string            `json:"recordId"`
+	Revision       int               `json:"revision"`
 	Symptomps      string            `json:"symptomps"`
 	Medications    string            `json:"medications"`
 	CreatedAt      string            `json:"createdAt"`
 	IdentityDetail RecordPatientBody `json:"identityDetail"`
 	CreatedBy      RecordUserBody    `json:"createdBy"`
+	UpdatedBy      RecordUserBody    `json:"updatedBy"`
+}
+
+// ToResponseBody credits Creator as createdBy and User as updatedBy.
+func (record *Record) ToResponseBody() (RecordResponseBody, error) {
+	createdBy, err := toRecordUserBody(record.Creator)
+	if err != nil {
+		return RecordResponseBody{}, err
+	}
+	updatedBy, err := toRecordUserBody(record.User)
+	if err != nil {
+		return RecordResponseBody{}, err
+	}
+
+	return RecordResponseBody{
+		RecordID:    record.RecordID.String(),
+		Revision:    record.Revision,
+		Symptomps:   record.Symptomps,
+		Medications: record.Medications,
+		CreatedAt: util.ToISO8601(
+			record.CreatedAt,
+		),
+		IdentityDetail: RecordPatientBody{
+			IdentityNumber: util.MaskIdentityNumber(
+				record.IdentityNumber,
+			),
+			PhoneNumber: util.MaskPhoneNumber(
+				record.Patient.PhoneNumber,
+			),
+			Name: record.Patient.Name,
+			Birthdate: util.ToISO8601(
+				record.Patient.Birthdate,
+			),
+			Gender:              record.Patient.Gender,
+			IdentityCardScanImg: record.Patient.IdentityScanImg,
+		},
+		CreatedBy: createdBy,
+		UpdatedBy: updatedBy,
+	}, nil
+}
+
+func toRecordUserBody(user User) (RecordUserBody, error) {
+	employeeIDUint, err := strconv.ParseUint(
+		user.EmployeeID,
+		10,
+		64,
+	)
+	if err != nil {
+		return RecordUserBody{}, err
+	}
+
+	return RecordUserBody{
+		NIP:    employeeIDUint,
+		Name:   user.Name,
+		UserID: user.ID.String(),
+	}, nil
+}
+
+type RecordRevisionResponseBody struct {
+	ID          string         `json:"id"`
+	RecordID    string         `json:"recordId"`
+	Revision    int            `json:"revision"`
+	Symptomps   string         `json:"symptomps"`
+	Medications string         `json:"medications"`
+	Reason      string         `json:"reason"`
+	CreatedAt   string         `json:"createdAt"`
+	CreatedBy   RecordUserBody `json:"createdBy"`
+}
+
+func (record *Record) ToRevisionResponseBody() (RecordRevisionResponseBody, error) {
+	createdBy, err := toRecordUserBody(record.User)
+	if err != nil {
+		return RecordRevisionResponseBody{}, err
+	}
+
+	return RecordRevisionResponseBody{
+		ID:          record.ID.String(),
+		RecordID:    record.RecordID.String(),
+		Revision:    record.Revision,
+		Symptomps:   record.Symptomps,
+		Medications: record.Medications,
+		Reason:      record.AmendReason,
+		// a revision is created when the record is updated
+		CreatedAt: util.ToISO8601(
+			record.UpdatedAt,
+		),
+		CreatedBy: createdBy,
+	}, nil
+}
+
+var RecordQueryFields = util.QueryFields{
+	"createdAt": {
+		Column:   "r.created_at",
+		Type:     util.FieldTime,
+		Sortable: true,
+		Ops:      util.RangeOps,
+	},
+}
+
+var RecordKeyset = util.Keyset{
+	Field:          "createdAt",
+	Column:         "r.created_at",
+	Tiebreaker:     "r.id",
+	TiebreakerType: util.FieldUUID,
+}
+
+type RecordQuery struct {
+	IdentityNumber string
+	// IdentityIndex is filled in by the repository
+	IdentityIndex string
+	UserID        string
+	NIP           string
+	CreatedAt     string
+	List          util.ListQuery
+	Offset        int
+	Limit         int
+}
+
+// BuildFilterClauses leaves out the cursor so the total can be counted.
+func (q *RecordQuery) BuildFilterClauses() ([]string, []interface{}) {
+	clauses := make([]string, 0, 3)
+	params := make([]interface{}, 0, 3)
+
+	if q.IdentityNumber != "" {
+		clauses = append(
+			clauses,
+			"r.identity_number = $%d",
+		)
+		params = append(
+			params,
+			q.IdentityIndex,
+		)
+	}
+
+	if q.UserID != "" {
+		clauses = append(
+			clauses,
+			"r.created_by = $%d",
+		)
+		params = append(
+			params,
+			q.UserID,
+		)
+	}
+
+	if q.NIP != "" {
+		clauses = append(
+			clauses,
+			"c.employee_id = $%d",
+		)
+		params = append(
+			params,
+			q.NIP,
+		)
+	}
+
+	listClauses, listParams := q.List.WhereClauses()
+	clauses = append(clauses, listClauses...)
+	params = append(params, listParams...)
+
+	return clauses, params
+}
+
+func (q *RecordQuery) BuildWhereClauses() ([]string, []interface{}) {
+	clauses, params := q.BuildFilterClauses()
+	cursorClause, cursorParams, ok := q.List.CursorClause(
+		RecordKeyset,
+		q.createdAtDesc(),
+	)
+	if ok {
+		clauses = append(clauses, cursorClause)
+		params = append(params, cursorParams...)
+	}
+
+	return clauses, params
+}
+
+func (q *RecordQuery) BuildPagination() (string, []interface{}) {
+	return q.List.Pagination(
+		q.Limit,
+		q.Offset,
+	)
+}
+
+func (q *RecordQuery) BuildOrderByClause() []string {
+	return q.List.OrderByClauses(
+		RecordKeyset,
+		q.createdAtDesc(),
+	)
+}
+
+// createdAtDesc honours the legacy createdAt=asc|desc.
+func (q *RecordQuery) createdAtDesc() bool {
+	return OrderBy(q.CreatedAt) != Asc
 }
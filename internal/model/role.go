@@ -0,0 +1,40 @@
+package model
+
+type Role struct {
+	Name        string
+	Permissions []string
+}
+
+type PermissionSet map[string]map[string]struct{}
+
+func NewPermissionSet(roles []Role) PermissionSet {
+	set := make(
+		PermissionSet,
+		len(roles),
+	)
+	for _, role := range roles {
+		permissions := make(
+			map[string]struct{},
+			len(role.Permissions),
+		)
+		for _, permission := range role.Permissions {
+			permissions[permission] = struct{}{}
+		}
+		set[role.Name] = permissions
+	}
+
+	return set
+}
+
+func (p PermissionSet) Allows(
+	roles []string,
+	permission string,
+) bool {
+	for _, role := range roles {
+		if _, ok := p[role][permission]; ok {
+			return true
+		}
+	}
+
+	return false
+}
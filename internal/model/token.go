@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefreshToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	FamilyID   uuid.UUID
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	ReplacedBy uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Session struct {
+	UserID       uuid.UUID
+	TokenID      uuid.UUID
+	TokenVersion int
+	ExpiresAt    time.Time
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type RefreshTokenRequestBody struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (body *RefreshTokenRequestBody) IsValid() error {
+	var validation ValidationError
+	validation.CheckLength("refreshToken", body.RefreshToken, 1, 100)
+
+	return validation.Err()
+}
+
+type LogoutRequestBody struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type TokenResponseBody struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+// TOTP only protects logins once EnabledAt is set.
+type TOTP struct {
+	UserID    uuid.UUID
+	Secret    string
+	EnabledAt time.Time
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+func (t *TOTP) Enabled() bool {
+	return !t.EnabledAt.IsZero()
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
+
+type TwoFactorEnrollResponseBody struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauthUrl"`
+}
+
+type TwoFactorVerifyBody struct {
+	Code string `json:"code"`
+}
+
+func (body *TwoFactorVerifyBody) IsValid() error {
+	var validation ValidationError
+	checkTOTPCode(&validation, "code", body.Code)
+
+	return validation.Err()
+}
+
+// TwoFactorVerifyResponseBody is the only time recovery codes are shown.
+type TwoFactorVerifyResponseBody struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorLoginBody struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+func (body *TwoFactorLoginBody) IsValid() error {
+	var validation ValidationError
+	if body.ChallengeToken == "" {
+		validation.Required("challengeToken")
+	}
+
+	switch {
+	case body.Code != "":
+		checkTOTPCode(&validation, "code", body.Code)
+	case body.RecoveryCode != "":
+		validation.CheckLength("recoveryCode", body.RecoveryCode, 1, 32)
+	default:
+		validation.Required("code")
+	}
+
+	return validation.Err()
+}
+
+func checkTOTPCode(
+	validation *ValidationError,
+	field string,
+	code string,
+) {
+	if code == "" {
+		validation.Required(field)
+		return
+	}
+	if len(code) != 6 {
+		validation.Format(field, constant.FormatTOTPCode)
+		return
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			validation.Format(field, constant.FormatTOTPCode)
+			return
+		}
+	}
+}
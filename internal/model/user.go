@@ -1,7 +1,8 @@
 package model
 
 import (
-	"fmt"
+	"errors"
+	"slices"
 	"strconv"
 	"time"
 
@@ -32,12 +33,24 @@ type User struct {
 	Name                 string
 	Password             string
 	IdentityCardImageURL string
-	CreatedBy            uuid.UUID
-	UpdatedBy            uuid.UUID
-	DeletedBy            uuid.UUID
-	CreatedAt            time.Time
-	UpdatedAt            time.Time
-	DeletedAt            time.Time
+	Roles                []string
+	TokenVersion         int
+	// MustChangePassword restricts the user's tokens to changing their password
+	MustChangePassword bool
+	HasAccess          bool
+	CreatedBy          uuid.UUID
+	UpdatedBy          uuid.UUID
+	DeletedBy          uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          time.Time
+}
+
+func (u *User) HasRole(role string) bool {
+	return slices.Contains(
+		u.Roles,
+		role,
+	)
 }
 
 func (u *User) ToUserDataResponseBody() (UserDataResponseBody, error) {
@@ -49,13 +62,23 @@ func (u *User) ToUserDataResponseBody() (UserDataResponseBody, error) {
 	if err != nil {
 		return UserDataResponseBody{}, err
 	}
+
+	var deletedAt string
+	if !u.DeletedAt.IsZero() {
+		deletedAt = util.ToISO8601(u.DeletedAt)
+	}
+
 	return UserDataResponseBody{
-		UserID: u.ID.String(),
-		NIP:    employeeIDInt,
-		Name:   u.Name,
+		UserID:    u.ID.String(),
+		NIP:       employeeIDInt,
+		Name:      u.Name,
+		HasAccess: u.HasAccess,
+		CreatedBy: userIDString(u.CreatedBy),
+		UpdatedBy: userIDString(u.UpdatedBy),
 		CreatedAt: util.ToISO8601(
 			u.CreatedAt,
 		),
+		DeletedAt: deletedAt,
 	}, nil
 }
 
@@ -70,9 +93,10 @@ func (u *User) ToUserRegisterResponseBody() (UserRegisterResponseBody, error) {
 	}
 
 	return UserRegisterResponseBody{
-		UserID: u.ID.String(),
-		NIP:    employeeIDInt,
-		Name:   u.Name,
+		UserID:             u.ID.String(),
+		NIP:                employeeIDInt,
+		Name:               u.Name,
+		MustChangePassword: u.MustChangePassword,
 	}, nil
 }
 
@@ -100,39 +124,38 @@ type UserRegisterRequestBody struct {
 }
 
 func (body *UserRegisterRequestBody) IsValid() (User, error) {
-	var user User
-	employeeIdString := strconv.FormatUint(
-		body.NIP,
-		10,
+	var (
+		user       User
+		validation ValidationError
+		err        error
 	)
-	err := util.ValidateUserEmployeeID(
-		employeeIdString,
+	user.EmployeeID, err = checkITEmployeeID(
+		&validation,
+		body.NIP,
 	)
 	if err != nil {
 		return user, err
 	}
-	user.EmployeeID = employeeIdString
 
-	if nameLen := len(body.Name); nameLen < 5 ||
-		nameLen > 50 {
-		return user, constant.ErrBadInput
-	}
+	validation.CheckLength("name", body.Name, 5, 50)
 	user.Name = body.Name
 
-	if passwordLen := len(body.Password); passwordLen < 5 ||
-		passwordLen > 33 {
-		return user, constant.ErrBadInput
-	}
+	validation.CheckLength("password", body.Password, 5, 33)
 	user.Password = body.Password
 
-	return user, nil
+	return user, validation.Err()
 }
 
 type UserRegisterResponseBody struct {
-	UserID      string `json:"userId"`
-	NIP         uint64 `json:"nip"`
-	Name        string `json:"name"`
-	AccessToken string `json:"accessToken"`
+	UserID       string `json:"userId"`
+	NIP          uint64 `json:"nip"`
+	Name         string `json:"name"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	// ChallengeToken replaces the tokens under two-factor authentication
+	ChallengeToken string `json:"challengeToken,omitempty"`
+	// MustChangePassword means the tokens only allow changing the password
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
 }
 
 type UserLoginBody struct {
@@ -141,26 +164,23 @@ type UserLoginBody struct {
 }
 
 func (body *UserLoginBody) IsValid() (User, error) {
-	var user User
-	employeeIdString := strconv.FormatUint(
-		body.NIP,
-		10,
+	var (
+		user       User
+		validation ValidationError
+		err        error
 	)
-	err := util.ValidateUserEmployeeID(
-		employeeIdString,
+	user.EmployeeID, err = checkITEmployeeID(
+		&validation,
+		body.NIP,
 	)
 	if err != nil {
 		return user, err
 	}
-	user.EmployeeID = employeeIdString
 
-	if passLen := len(body.Password); passLen < 5 ||
-		passLen > 33 {
-		return user, constant.ErrBadInput
-	}
+	validation.CheckLength("password", body.Password, 5, 33)
 	user.Password = body.Password
 
-	return user, nil
+	return user, validation.Err()
 }
 
 type NurseLoginBody struct {
@@ -169,26 +189,19 @@ type NurseLoginBody struct {
 }
 
 func (body *NurseLoginBody) IsValid() (User, error) {
-	var user User
-	employeeIdString := strconv.FormatUint(
-		body.NIP,
-		10,
+	var (
+		user       User
+		validation ValidationError
 	)
-	err := util.ValidateGeneralEmployeeID(
-		employeeIdString,
+	user.EmployeeID = checkEmployeeID(
+		&validation,
+		body.NIP,
 	)
-	if err != nil {
-		return user, err
-	}
-	user.EmployeeID = employeeIdString
 
-	if passLen := len(body.Password); passLen < 5 ||
-		passLen > 33 {
-		return user, constant.ErrBadInput
-	}
+	validation.CheckLength("password", body.Password, 5, 33)
 	user.Password = body.Password
 
-	return user, nil
+	return user, validation.Err()
 }
 
 type NurseRegisterRequestBody struct {
@@ -198,46 +211,69 @@ type NurseRegisterRequestBody struct {
 }
 
 func (body *NurseRegisterRequestBody) IsValid() (User, error) {
-	var user User
-	employeeIdString := strconv.FormatUint(
-		body.NIP,
-		10,
+	var (
+		user       User
+		validation ValidationError
 	)
-	err := util.ValidateGeneralEmployeeID(
-		employeeIdString,
+	user.EmployeeID = checkEmployeeID(
+		&validation,
+		body.NIP,
 	)
-	if err != nil {
-		return user, err
-	}
-	user.EmployeeID = employeeIdString
 
-	if nameLen := len(body.Name); nameLen < 5 ||
-		nameLen > 50 {
-		return user, constant.ErrBadInput
-	}
+	validation.CheckLength("name", body.Name, 5, 50)
 	user.Name = body.Name
 
-	if !util.ValidateURL(
+	checkURL(
+		&validation,
+		"identityCardScanImg",
 		body.IdentityCardScanImg,
-	) {
-		return user, constant.ErrBadInput
-	}
+	)
 	user.IdentityCardImageURL = body.IdentityCardScanImg
 
-	return user, nil
+	return user, validation.Err()
 }
 
 type NurseGiveAccessRequestBody struct {
 	Password string `json:"password"`
 }
 
-func (body *NurseGiveAccessRequestBody) IsValid() bool {
-	if passLen := len(body.Password); passLen < 5 ||
-		passLen > 33 {
-		return false
+func (body *NurseGiveAccessRequestBody) IsValid() error {
+	var validation ValidationError
+	validation.CheckLength("password", body.Password, 5, 33)
+
+	return validation.Err()
+}
+
+// NursePurgeRequestBody confirms a purge by repeating the nurse's NIP.
+type NursePurgeRequestBody struct {
+	NIP        uint64 `json:"nip"`
+	ReassignTo string `json:"reassignTo"`
+}
+
+type NursePurge struct {
+	EmployeeID string
+	ReassignTo uuid.UUID
+}
+
+func (body *NursePurgeRequestBody) IsValid() (NursePurge, error) {
+	var (
+		purge      NursePurge
+		validation ValidationError
+	)
+	purge.EmployeeID = checkEmployeeID(
+		&validation,
+		body.NIP,
+	)
+
+	if body.ReassignTo != "" {
+		reassignTo, err := uuid.Parse(body.ReassignTo)
+		if err != nil {
+			validation.Format("reassignTo", constant.FormatUUID)
+		}
+		purge.ReassignTo = reassignTo
 	}
 
-	return true
+	return purge, validation.Err()
 }
 
 type NurseEditRequestBody struct {
@@ -246,26 +282,62 @@ type NurseEditRequestBody struct {
 }
 
 func (body *NurseEditRequestBody) IsValid() (User, error) {
-	var user User
-	employeeIdString := strconv.FormatUint(
+	var (
+		user       User
+		validation ValidationError
+	)
+	user.EmployeeID = checkEmployeeID(
+		&validation,
 		body.NIP,
+	)
+
+	validation.CheckLength("name", body.Name, 5, 50)
+	user.Name = body.Name
+
+	return user, validation.Err()
+}
+
+// checkITEmployeeID reports the NIP of another role as constant.ErrNotFound.
+func checkITEmployeeID(
+	validation *ValidationError,
+	nip uint64,
+) (string, error) {
+	employeeIdString := strconv.FormatUint(
+		nip,
 		10,
 	)
-	err := util.ValidateGeneralEmployeeID(
+	err := util.ValidateUserEmployeeID(
 		employeeIdString,
 	)
-	if err != nil {
-		return user, err
+	switch {
+	case nip == 0:
+		validation.Required("nip")
+	case errors.Is(err, constant.ErrNotFound):
+		return employeeIdString, err
+	case err != nil:
+		validation.Format("nip", constant.FormatITNIP)
 	}
-	user.EmployeeID = employeeIdString
 
-	if nameLen := len(body.Name); nameLen < 5 ||
-		nameLen > 50 {
-		return user, constant.ErrBadInput
+	return employeeIdString, nil
+}
+
+func checkEmployeeID(
+	validation *ValidationError,
+	nip uint64,
+) string {
+	employeeIdString := strconv.FormatUint(
+		nip,
+		10,
+	)
+	if nip == 0 {
+		validation.Required("nip")
+	} else if util.ValidateGeneralEmployeeID(
+		employeeIdString,
+	) != nil {
+		validation.Format("nip", constant.FormatNIP)
 	}
-	user.Name = body.Name
 
-	return user, nil
+	return employeeIdString
 }
 
 type NurseRegisterResponseBody struct {
@@ -274,17 +346,48 @@ type NurseRegisterResponseBody struct {
 	Name   string `json:"name"`
 }
 
+var UserQueryFields = util.QueryFields{
+	"nip": {
+		Column:   "employee_id",
+		Type:     util.FieldString,
+		Sortable: true,
+		Ops:      util.TextOps,
+	},
+	"name": {
+		Column:   "name",
+		Type:     util.FieldString,
+		Sortable: true,
+		Ops:      util.TextOps,
+	},
+	"createdAt": {
+		Column:   "created_at",
+		Type:     util.FieldTime,
+		Sortable: true,
+		Ops:      util.RangeOps,
+	},
+}
+
+var UserKeyset = util.Keyset{
+	Field:          "createdAt",
+	Column:         "created_at",
+	Tiebreaker:     "id",
+	TiebreakerType: util.FieldUUID,
+}
+
 type SearchUserQuery struct {
-	UserID    string  `query:"userId"`
-	Name      string  `query:"name"`
-	NIP       uint64  `query:"nip"`
-	Role      string  `query:"role"`
-	CreatedAt OrderBy `query:"createdAt"`
+	UserID    string         `query:"userId"`
+	Name      string         `query:"name"`
+	NIP       uint64         `query:"nip"`
+	Role      string         `query:"role"`
+	Deleted   bool           `query:"deleted"`
+	CreatedAt OrderBy        `query:"createdAt"`
+	List      util.ListQuery `query:"-"`
 	Offset    int
 	Limit     int
 }
 
-func (q *SearchUserQuery) BuildWhereClauses() ([]string, []interface{}) {
+// BuildFilterClauses leaves out the cursor so the total can be counted.
+func (q *SearchUserQuery) BuildFilterClauses() ([]string, []interface{}) {
 	clauses := make([]string, 0, 4)
 	params := make([]interface{}, 0, 4)
 
@@ -299,103 +402,128 @@ func (q *SearchUserQuery) BuildWhereClauses() ([]string, []interface{}) {
 		)
 	}
 
-	if q.Name != "" {
-		clauses = append(
-			clauses,
-			`name ilike '%%' || $%d || '%%'`,
-		)
-		params = append(params, q.Name)
-	}
-
-	employeeIdString := strconv.FormatUint(
-		q.NIP,
-		10,
-	)
-	if employeeIdString != "0" &&
-		employeeIdString != "" {
+	if q.Role != "" {
 		clauses = append(
 			clauses,
-			`employee_id like $%d || '%%'`,
+			`exists (
+        select 1
+        from user_roles ur
+        where ur.user_id = users.id and
+          ur.role_name = $%d
+      )`,
 		)
 		params = append(
 			params,
-			employeeIdString,
+			q.Role,
 		)
 	}
 
-	switch q.Role {
-	case "it":
+	// purged users are gone for good, even though their row may be left
+	if q.Deleted {
 		clauses = append(
 			clauses,
-			`employee_id like $%d || '%%'`,
-		)
-
-		params = append(
-			params,
-			"615",
+			"deleted_at is not null and purged_at is null",
 		)
-
-	case "nurse":
+	} else {
 		clauses = append(
 			clauses,
-			"employee_id like $%d || '%%'",
-		)
-		params = append(
-			params,
-			"303",
+			"deleted_at is null",
 		)
 	}
 
+	list := q.List
+	list.Filters = append(
+		slices.Clip(q.List.Filters),
+		q.legacyFilters()...,
+	)
+	listClauses, listParams := list.WhereClauses()
+	clauses = append(clauses, listClauses...)
+	params = append(params, listParams...)
+
 	return clauses, params
 }
 
-func (q SearchUserQuery) BuildPagination() (string, []interface{}) {
-	var params []interface{}
-
-	limit := 5
-	offset := 0
-	if q.Limit > 0 {
-		limit = q.Limit
+// legacyFilters maps name and nip onto name[contains] and nip[prefix].
+func (q *SearchUserQuery) legacyFilters() []util.FilterClause {
+	var filters []util.FilterClause
+	if q.Name != "" {
+		filters = append(
+			filters,
+			util.FilterClause{
+				Field:  "name",
+				Column: UserQueryFields["name"].Column,
+				Op:     util.FilterContains,
+				Value:  q.Name,
+			},
+		)
 	}
-	if q.Offset > 0 {
-		offset = q.Offset
+
+	if q.NIP != 0 {
+		filters = append(
+			filters,
+			util.FilterClause{
+				Field:  "nip",
+				Column: UserQueryFields["nip"].Column,
+				Op:     util.FilterPrefix,
+				Value: strconv.FormatUint(
+					q.NIP,
+					10,
+				),
+			},
+		)
 	}
-	params = append(
-		params,
-		limit,
-		offset,
+
+	return filters
+}
+
+func (q *SearchUserQuery) BuildWhereClauses() ([]string, []interface{}) {
+	clauses, params := q.BuildFilterClauses()
+	cursorClause, cursorParams, ok := q.List.CursorClause(
+		UserKeyset,
+		q.createdAtDesc(),
 	)
+	if ok {
+		clauses = append(clauses, cursorClause)
+		params = append(params, cursorParams...)
+	}
+
+	return clauses, params
+}
 
-	return "limit $%d offset $%d", params
+func (q SearchUserQuery) BuildPagination() (string, []interface{}) {
+	return q.List.Pagination(
+		q.Limit,
+		q.Offset,
+	)
 }
 
 func (q SearchUserQuery) BuildOrderByClause() []string {
-	var sqlClause []string
-
-	if q.CreatedAt != "" ||
-		OrderBy(
-			q.CreatedAt,
-		).IsValid() {
-		sqlClause = append(
-			sqlClause,
-			fmt.Sprintf(
-				"created_at %s",
-				q.CreatedAt,
-			),
-		)
-	} else {
-		sqlClause = append(
-			sqlClause,
-			"created_at desc",
-		)
-	}
+	return q.List.OrderByClauses(
+		UserKeyset,
+		q.createdAtDesc(),
+	)
+}
 
-	return sqlClause
+// createdAtDesc honours the legacy createdAt=asc|desc.
+func (q SearchUserQuery) createdAtDesc() bool {
+	return q.CreatedAt != Asc
 }
 
 type UserDataResponseBody struct {
 	UserID    string `json:"userId"`
 	NIP       uint64 `json:"nip"`
 	Name      string `json:"name"`
+	HasAccess bool   `json:"hasAccess"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	UpdatedBy string `json:"updatedBy,omitempty"`
 	CreatedAt string `json:"createdAt"`
+	DeletedAt string `json:"deletedAt,omitempty"`
+}
+
+// userIDString is empty for rows written before provenance was recorded.
+func userIDString(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
 }
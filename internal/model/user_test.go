@@ -0,0 +1,60 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+func TestSearchUserQueryLegacyFilters(t *testing.T) {
+	list, err := util.ParseListQuery(
+		"",
+		map[string]string{
+			"name[eq]":     "Siti Rahayu",
+			"nip[prefix]":  "3031",
+			"name":         "siti",
+			"createdAt":    "asc",
+			"nip":          "303120",
+			"unrelatedKey": "ignored",
+		},
+		UserQueryFields,
+		UserKeyset,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := SearchUserQuery{
+		Name: "siti",
+		NIP:  303120,
+		List: list,
+	}
+	clauses, params := queries.BuildFilterClauses()
+
+	// the legacy parameters add to the grammar's filters, all of them apply
+	wantClauses := []string{
+		"deleted_at is null",
+		"name = $%d",
+		"employee_id like $%d || '%%'",
+		"name ilike '%%' || $%d || '%%'",
+		"employee_id like $%d || '%%'",
+	}
+	if !slices.Equal(clauses, wantClauses) {
+		t.Errorf("clauses = %q, want %q", clauses, wantClauses)
+	}
+	wantParams := []interface{}{
+		"Siti Rahayu",
+		"3031",
+		"siti",
+		"303120",
+	}
+	if !slices.Equal(params, wantParams) {
+		t.Errorf("params = %v, want %v", params, wantParams)
+	}
+
+	// rendering must not leak the legacy filters into the parsed query
+	if len(queries.List.Filters) != 2 {
+		t.Errorf("List.Filters = %v, want the 2 parsed filters", queries.List.Filters)
+	}
+}
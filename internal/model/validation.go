@@ -0,0 +1,212 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/i18n"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// ValidationError matches constant.ErrBadInput with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (v *ValidationError) Error() string {
+	return constant.ErrBadInput.Error()
+}
+
+func (v *ValidationError) Is(target error) bool {
+	return target == constant.ErrBadInput
+}
+
+func (v *ValidationError) Err() error {
+	if len(v.Fields) == 0 {
+		return nil
+	}
+
+	return v
+}
+
+func (v *ValidationError) Localize(lang i18n.Lang) []FieldError {
+	fields := make(
+		[]FieldError,
+		0,
+		len(v.Fields),
+	)
+	for _, field := range v.Fields {
+		field.Message = i18n.FieldMessage(
+			lang,
+			field.Code,
+			field.Params,
+		)
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+func (v *ValidationError) Required(field string) {
+	v.add(
+		field,
+		constant.ValidationRequired,
+		nil,
+	)
+}
+
+func (v *ValidationError) Length(
+	field string,
+	min, max int,
+) {
+	v.add(
+		field,
+		constant.ValidationLength,
+		map[string]interface{}{
+			"min": min,
+			"max": max,
+		},
+	)
+}
+
+func (v *ValidationError) Format(
+	field string,
+	format string,
+) {
+	v.add(
+		field,
+		constant.ValidationFormat,
+		map[string]interface{}{
+			"format": format,
+		},
+	)
+}
+
+func (v *ValidationError) OneOf(
+	field string,
+	values ...string,
+) {
+	v.add(
+		field,
+		constant.ValidationOneOf,
+		map[string]interface{}{
+			"values": values,
+		},
+	)
+}
+
+func (v *ValidationError) FileSize(
+	field string,
+	max int64,
+) {
+	v.add(
+		field,
+		constant.ValidationFileSize,
+		map[string]interface{}{
+			"max": max,
+		},
+	)
+}
+
+func (v *ValidationError) Character(
+	field string,
+	class string,
+) {
+	v.add(
+		field,
+		constant.ValidationCharacter,
+		map[string]interface{}{
+			"class": class,
+		},
+	)
+}
+
+func (v *ValidationError) Reused(field string) {
+	v.add(
+		field,
+		constant.ValidationReused,
+		nil,
+	)
+}
+
+func (v *ValidationError) CheckLength(
+	field string,
+	value string,
+	min, max int,
+) {
+	if value == "" {
+		v.Required(field)
+	} else if valueLen := len(value); valueLen < min ||
+		valueLen > max {
+		v.Length(field, min, max)
+	}
+}
+
+func (v *ValidationError) add(
+	field string,
+	code string,
+	params map[string]interface{},
+) {
+	v.Fields = append(
+		v.Fields,
+		FieldError{
+			Field: field,
+			Code:  code,
+			Message: i18n.FieldMessage(
+				i18n.Default,
+				code,
+				params,
+			),
+			Params: params,
+		},
+	)
+}
+
+func checkURL(
+	validation *ValidationError,
+	field string,
+	url string,
+) {
+	if url == "" {
+		validation.Required(field)
+	} else if !util.ValidateURL(url) {
+		validation.Format(field, constant.FormatURL)
+	}
+}
+
+// ParseListQuery reports a *util.QueryError as a ValidationError.
+func ParseListQuery(
+	sort string,
+	queries map[string]string,
+	fields util.QueryFields,
+	keyset util.Keyset,
+) (util.ListQuery, error) {
+	listQuery, err := util.ParseListQuery(
+		sort,
+		queries,
+		fields,
+		keyset,
+	)
+	var queryErr *util.QueryError
+	if errors.As(
+		err,
+		&queryErr,
+	) {
+		var validation ValidationError
+		validation.add(
+			queryErr.Param,
+			queryErr.Code,
+			queryErr.Params,
+		)
+		return util.ListQuery{}, validation.Err()
+	}
+
+	return listQuery, err
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+func TestParseListQueryValidationError(t *testing.T) {
+	_, err := ParseListQuery(
+		"",
+		map[string]string{"nip[gt]": "3031"},
+		UserQueryFields,
+		UserKeyset,
+	)
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("ParseListQuery = %v, want a *ValidationError", err)
+	}
+	if len(validation.Fields) != 1 {
+		t.Fatalf("Fields = %v, want one field", validation.Fields)
+	}
+	field := validation.Fields[0]
+	if field.Field != "nip[gt]" || field.Code != constant.ValidationOneOf {
+		t.Errorf("field = %s %s, want nip[gt] %s", field.Field, field.Code, constant.ValidationOneOf)
+	}
+	if field.Message != "must be one of nip[eq], nip[ne], nip[contains], nip[prefix]" {
+		t.Errorf("Message = %q", field.Message)
+	}
+}
@@ -0,0 +1,345 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+// ErrRejected means the provider refused the login, as opposed to being unreachable.
+var ErrRejected = errors.New(
+	"rejected by OpenID provider",
+)
+
+// signingMethods leaves out HMAC, the client secret must not sign ID tokens.
+var signingMethods = []string{
+	"RS256",
+	"RS384",
+	"RS512",
+	"PS256",
+	"ES256",
+	"ES384",
+	"EdDSA",
+}
+
+type discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningMethods        []string `json:"id_token_signing_alg_values_supported"`
+}
+
+type Provider struct {
+	client       *http.Client
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       string
+
+	// mu guards discovered and jwks, which are filled in on first use
+	mu         sync.Mutex
+	discovered *discovery
+	jwks       *keyfunc.JWKS
+}
+
+func NewProvider(cfg config.OIDCConfig) (*Provider, error) {
+	if cfg.OIDCClientID == "" ||
+		cfg.OIDCRedirectURL == "" {
+		return nil, errors.New(
+			"OIDC_ISSUER needs OIDC_CLIENT_ID and OIDC_REDIRECT_URL",
+		)
+	}
+	if !slices.Contains(
+		strings.Fields(cfg.OIDCScopes),
+		"openid",
+	) {
+		return nil, errors.New(
+			"OIDC_SCOPES must include openid",
+		)
+	}
+
+	return &Provider{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		issuer:       strings.TrimRight(cfg.OIDCIssuer, "/"),
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL:  cfg.OIDCRedirectURL,
+		scopes:       cfg.OIDCScopes,
+	}, nil
+}
+
+func (p *Provider) AuthCodeURL(
+	ctx context.Context,
+	state string,
+	nonce string,
+	codeVerifier string,
+) (string, error) {
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovered.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	params := authURL.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", p.redirectURL)
+	params.Set("scope", p.scopes)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+	authURL.RawQuery = params.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange leaves checking the nonce to the caller.
+func (p *Provider) Exchange(
+	ctx context.Context,
+	code string,
+	codeVerifier string,
+) (jwt.MapClaims, error) {
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		discovered.TokenEndpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic form-encodes both halves, RFC 6749 2.3.1
+	req.SetBasicAuth(
+		url.QueryEscape(p.clientID),
+		url.QueryEscape(p.clientSecret),
+	)
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	err = p.do(
+		req,
+		&tokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf(
+			"%w: token response carries no id_token",
+			ErrRejected,
+		)
+	}
+
+	return p.verify(
+		discovered,
+		tokens.IDToken,
+	)
+}
+
+func (p *Provider) verify(
+	discovered *discovery,
+	idToken string,
+) (jwt.MapClaims, error) {
+	methods := signingMethods
+	if len(discovered.SigningMethods) > 0 {
+		methods = slices.DeleteFunc(
+			slices.Clone(discovered.SigningMethods),
+			func(method string) bool {
+				return !slices.Contains(signingMethods, method)
+			},
+		)
+	}
+
+	token, err := jwt.Parse(
+		idToken,
+		p.jwks.Keyfunc,
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(discovered.Issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			ErrRejected,
+			err,
+		)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrRejected
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf(
+			"%w: ID token has no subject",
+			ErrRejected,
+		)
+	}
+	// a token meant for several clients names the one it was issued to
+	audience, _ := claims.GetAudience()
+	if len(audience) > 1 && claims["azp"] != p.clientID {
+		return nil, fmt.Errorf(
+			"%w: ID token was issued to %v",
+			ErrRejected,
+			claims["azp"],
+		)
+	}
+
+	return claims, nil
+}
+
+// discover is retried on the next call when it fails.
+func (p *Provider) discover(
+	ctx context.Context,
+) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered != nil {
+		return p.discovered, nil
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		p.issuer+"/.well-known/openid-configuration",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var discovered discovery
+	err = p.do(
+		req,
+		&discovered,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(discovered.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf(
+			"OpenID provider calls itself %q, expected %q",
+			discovered.Issuer,
+			p.issuer,
+		)
+	}
+	if discovered.AuthorizationEndpoint == "" ||
+		discovered.TokenEndpoint == "" ||
+		discovered.JWKSURI == "" {
+		return nil, errors.New(
+			"OpenID provider does not publish its endpoints",
+		)
+	}
+
+	// an unknown kid may be a key published since
+	jwks, err := keyfunc.Get(
+		discovered.JWKSURI,
+		keyfunc.Options{
+			Client:            p.client,
+			RefreshUnknownKID: true,
+			RefreshRateLimit:  time.Minute,
+			RefreshTimeout:    p.client.Timeout,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.discovered = &discovered
+	p.jwks = jwks
+
+	return p.discovered, nil
+}
+
+// do wraps client errors in ErrRejected.
+func (p *Provider) do(
+	req *http.Request,
+	v any,
+) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(
+			io.LimitReader(res.Body, 1024),
+		)
+		err = fmt.Errorf(
+			"%s %s: %s: %s",
+			req.Method,
+			req.URL.Redacted(),
+			res.Status,
+			message,
+		)
+		if res.StatusCode >= 400 && res.StatusCode < 500 {
+			err = fmt.Errorf(
+				"%w: %v",
+				ErrRejected,
+				err,
+			)
+		}
+		return err
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// RandomString returns 256 random bits, base64url encoded.
+func RandomString() (string, error) {
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// codeChallenge is the S256 PKCE challenge of verifier, RFC 7636 4.2.
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,357 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+var ErrUnknownKeyVersion = errors.New(
+	"unknown PII key version",
+)
+
+// Keyring holds the versioned keys wrapping the per-row data keys, and the blind index key.
+type Keyring struct {
+	keys     map[int][]byte
+	active   int
+	indexKey []byte
+}
+
+func NewKeyring(cfg config.PIIConfig) (*Keyring, error) {
+	keyring := &Keyring{
+		keys: make(map[int][]byte),
+	}
+
+	for _, entry := range strings.Split(cfg.PIIKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		versionString, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf(
+				"PII_KEYS: %q is not version:key",
+				entry,
+			)
+		}
+		version, err := strconv.Atoi(versionString)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf(
+				"PII_KEYS: invalid version %q",
+				versionString,
+			)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"PII_KEYS: version %d: %w",
+				version,
+				err,
+			)
+		}
+		if _, exists := keyring.keys[version]; exists {
+			return nil, fmt.Errorf(
+				"PII_KEYS: version %d is listed twice",
+				version,
+			)
+		}
+
+		keyring.keys[version] = key
+		if version > keyring.active {
+			keyring.active = version
+		}
+	}
+	if len(keyring.keys) == 0 {
+		return nil, errors.New("PII_KEYS is not set")
+	}
+
+	if cfg.PIIActiveKeyVersion != 0 {
+		if _, ok := keyring.keys[cfg.PIIActiveKeyVersion]; !ok {
+			return nil, fmt.Errorf(
+				"PII_ACTIVE_KEY_VERSION %d is not in PII_KEYS",
+				cfg.PIIActiveKeyVersion,
+			)
+		}
+		keyring.active = cfg.PIIActiveKeyVersion
+	}
+
+	indexKey, err := decodeKey(cfg.PIIIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("PII_INDEX_KEY: %w", err)
+	}
+	keyring.indexKey = indexKey
+
+	return keyring, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(
+		strings.TrimSpace(encoded),
+	)
+	if err != nil {
+		return nil, errors.New("key is not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, errors.New("key must be 32 bytes")
+	}
+
+	return key, nil
+}
+
+func (k *Keyring) ActiveVersion() int {
+	return k.active
+}
+
+func (k *Keyring) NewEnvelope() (*Envelope, error) {
+	dataKey := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := seal(
+		k.keys[k.active],
+		dataKey,
+		[]byte("data-key"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		dataKey:    dataKey,
+		WrappedKey: wrapped,
+		KeyVersion: k.active,
+	}, nil
+}
+
+func (k *Keyring) OpenEnvelope(
+	wrappedKey []byte,
+	keyVersion int,
+) (*Envelope, error) {
+	key, ok := k.keys[keyVersion]
+	if !ok {
+		return nil, fmt.Errorf(
+			"%w %d",
+			ErrUnknownKeyVersion,
+			keyVersion,
+		)
+	}
+
+	dataKey, err := open(
+		key,
+		wrappedKey,
+		[]byte("data-key"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		dataKey:    dataKey,
+		WrappedKey: wrappedKey,
+		KeyVersion: keyVersion,
+	}, nil
+}
+
+// Rewrap re-encrypts a data key with the active key.
+func (k *Keyring) Rewrap(
+	wrappedKey []byte,
+	keyVersion int,
+) ([]byte, error) {
+	envelope, err := k.OpenEnvelope(
+		wrappedKey,
+		keyVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return seal(
+		k.keys[k.active],
+		envelope.dataKey,
+		[]byte("data-key"),
+	)
+}
+
+// BlindIndex is a keyed hash of value, distinct for every purpose.
+func (k *Keyring) BlindIndex(
+	purpose string,
+	value string,
+) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PrefixIndex returns the blind index of every prefix of value.
+func (k *Keyring) PrefixIndex(
+	purpose string,
+	value string,
+) []string {
+	index := make(
+		[]string,
+		0,
+		utf8.RuneCountInString(value),
+	)
+	for i := range value {
+		if i == 0 {
+			continue
+		}
+		index = append(
+			index,
+			k.BlindIndex(purpose, value[:i]),
+		)
+	}
+	if value != "" {
+		index = append(
+			index,
+			k.BlindIndex(purpose, value),
+		)
+	}
+
+	return index
+}
+
+// TokenPrefixIndex returns the prefix index of every lowercased word.
+func (k *Keyring) TokenPrefixIndex(
+	purpose string,
+	value string,
+) []string {
+	var index []string
+	for _, token := range Tokens(value) {
+		index = append(
+			index,
+			k.PrefixIndex(purpose, token)...,
+		)
+	}
+
+	return index
+}
+
+// TokenIndex is matched against a TokenPrefixIndex.
+func (k *Keyring) TokenIndex(
+	purpose string,
+	value string,
+) []string {
+	tokens := Tokens(value)
+	index := make(
+		[]string,
+		0,
+		len(tokens),
+	)
+	for _, token := range tokens {
+		index = append(
+			index,
+			k.BlindIndex(purpose, token),
+		)
+	}
+
+	return index
+}
+
+func Tokens(value string) []string {
+	return strings.Fields(
+		strings.ToLower(value),
+	)
+}
+
+type Envelope struct {
+	dataKey    []byte
+	WrappedKey []byte
+	KeyVersion int
+}
+
+// Seal binds the ciphertext to its column and row through aad.
+func (e *Envelope) Seal(
+	value string,
+	aad string,
+) ([]byte, error) {
+	return seal(
+		e.dataKey,
+		[]byte(value),
+		[]byte(aad),
+	)
+}
+
+func (e *Envelope) Open(
+	ciphertext []byte,
+	aad string,
+) (string, error) {
+	plaintext, err := open(
+		e.dataKey,
+		ciphertext,
+		[]byte(aad),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// seal encrypts with AES-256-GCM and prepends the random nonce.
+func seal(
+	key []byte,
+	plaintext []byte,
+	aad []byte,
+) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(
+	key []byte,
+	ciphertext []byte,
+	aad []byte,
+) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return aead.Open(
+		nil,
+		ciphertext[:aead.NonceSize()],
+		ciphertext[aead.NonceSize():],
+		aad,
+	)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
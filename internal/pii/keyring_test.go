@@ -0,0 +1,267 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(
+		bytes.Repeat([]byte{b}, 32),
+	)
+}
+
+func newTestKeyring(
+	t *testing.T,
+	keys string,
+	active int,
+) *Keyring {
+	t.Helper()
+
+	keyring, err := NewKeyring(
+		config.PIIConfig{
+			PIIKeys:             keys,
+			PIIActiveKeyVersion: active,
+			PIIIndexKey:         testKey(9),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyring
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.PIIConfig
+		active  int
+		wantErr bool
+	}{
+		{
+			name: "highest version is active",
+			cfg: config.PIIConfig{
+				PIIKeys:     "1:" + testKey(1) + ", 3:" + testKey(3) + ",2:" + testKey(2),
+				PIIIndexKey: testKey(9),
+			},
+			active: 3,
+		},
+		{
+			name: "explicit active version",
+			cfg: config.PIIConfig{
+				PIIKeys:             "1:" + testKey(1) + ",2:" + testKey(2),
+				PIIActiveKeyVersion: 1,
+				PIIIndexKey:         testKey(9),
+			},
+			active: 1,
+		},
+		{
+			name: "no keys",
+			cfg: config.PIIConfig{
+				PIIIndexKey: testKey(9),
+			},
+			wantErr: true,
+		},
+		{
+			name: "active version not listed",
+			cfg: config.PIIConfig{
+				PIIKeys:             "1:" + testKey(1),
+				PIIActiveKeyVersion: 2,
+				PIIIndexKey:         testKey(9),
+			},
+			wantErr: true,
+		},
+		{
+			name: "version listed twice",
+			cfg: config.PIIConfig{
+				PIIKeys:     "1:" + testKey(1) + ",1:" + testKey(2),
+				PIIIndexKey: testKey(9),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid version",
+			cfg: config.PIIConfig{
+				PIIKeys:     "0:" + testKey(1),
+				PIIIndexKey: testKey(9),
+			},
+			wantErr: true,
+		},
+		{
+			name: "short key",
+			cfg: config.PIIConfig{
+				PIIKeys:     "1:" + base64.StdEncoding.EncodeToString([]byte("short")),
+				PIIIndexKey: testKey(9),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing index key",
+			cfg: config.PIIConfig{
+				PIIKeys: "1:" + testKey(1),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyring, err := NewKeyring(test.cfg)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("NewKeyring succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keyring.ActiveVersion() != test.active {
+				t.Errorf("ActiveVersion = %d, want %d", keyring.ActiveVersion(), test.active)
+			}
+		})
+	}
+}
+
+func TestEnvelopeSealOpen(t *testing.T) {
+	keyring := newTestKeyring(t, "1:"+testKey(1), 0)
+
+	envelope, err := keyring.NewEnvelope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := envelope.Seal(
+		"3271010101010001",
+		"patient.identity_number:abc",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("3271010101010001")) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	// a row read back unwraps its data key from the stored form
+	opened, err := keyring.OpenEnvelope(
+		envelope.WrappedKey,
+		envelope.KeyVersion,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := opened.Open(
+		ciphertext,
+		"patient.identity_number:abc",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "3271010101010001" {
+		t.Errorf("Open = %q, want %q", plaintext, "3271010101010001")
+	}
+
+	_, err = opened.Open(
+		ciphertext,
+		"patient.phone_number:abc",
+	)
+	if err == nil {
+		t.Error("Open accepted a ciphertext moved to another column")
+	}
+
+	tampered := slices.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	_, err = opened.Open(
+		tampered,
+		"patient.identity_number:abc",
+	)
+	if err == nil {
+		t.Error("Open accepted a tampered ciphertext")
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	oldKeyring := newTestKeyring(t, "1:"+testKey(1), 0)
+	envelope, err := oldKeyring.NewEnvelope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := envelope.Seal(
+		"Budi Santoso",
+		"patient.name:abc",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// version 2 is added and made active, version 1 still unwraps
+	keyring := newTestKeyring(t, "1:"+testKey(1)+",2:"+testKey(2), 0)
+	rewrapped, err := keyring.Rewrap(
+		envelope.WrappedKey,
+		envelope.KeyVersion,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// once version 1 is removed, the rewrapped key opens the same data
+	newKeyring := newTestKeyring(t, "2:"+testKey(2), 0)
+	opened, err := newKeyring.OpenEnvelope(
+		rewrapped,
+		2,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := opened.Open(
+		ciphertext,
+		"patient.name:abc",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "Budi Santoso" {
+		t.Errorf("Open = %q, want %q", plaintext, "Budi Santoso")
+	}
+
+	_, err = newKeyring.OpenEnvelope(
+		envelope.WrappedKey,
+		1,
+	)
+	if !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Errorf("OpenEnvelope = %v, want %v", err, ErrUnknownKeyVersion)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keyring := newTestKeyring(t, "1:"+testKey(1), 0)
+
+	index := keyring.BlindIndex("patient.identity_number", "3271010101010001")
+	if index != keyring.BlindIndex("patient.identity_number", "3271010101010001") {
+		t.Error("BlindIndex is not deterministic")
+	}
+	if index == keyring.BlindIndex("patient.phone_number", "3271010101010001") {
+		t.Error("BlindIndex does not depend on the purpose")
+	}
+	if len(index) != 64 {
+		t.Errorf("BlindIndex has %d characters, want 64", len(index))
+	}
+
+	other, err := NewKeyring(
+		config.PIIConfig{
+			PIIKeys:     "1:" + testKey(1),
+			PIIIndexKey: testKey(8),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index == other.BlindIndex("patient.identity_number", "3271010101010001") {
+		t.Error("BlindIndex does not depend on the index key")
+	}
+}
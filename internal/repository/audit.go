@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/pii"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+// AuditRepository references patients by their blind index.
+type AuditRepository struct {
+	db      *pgxpool.Pool
+	keyring *pii.Keyring
+}
+
+func NewAuditRepository(
+	db *pgxpool.Pool,
+	keyring *pii.Keyring,
+) *AuditRepository {
+	return &AuditRepository{
+		db:      db,
+		keyring: keyring,
+	}
+}
+
+func (r *AuditRepository) Create(
+	ctx context.Context,
+	events []model.AuditEvent,
+) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	_, err := conn(ctx, r.db).CopyFrom(
+		ctx,
+		pgx.Identifier{"audit_events"},
+		[]string{
+			"id",
+			"actor_id",
+			"action",
+			"target_type",
+			"target_id",
+			"request_id",
+			"ip",
+			"created_at",
+		},
+		pgx.CopyFromSlice(
+			len(events),
+			func(i int) ([]any, error) {
+				event := events[i]
+				return []any{
+					event.ID,
+					nullableUUID(event.ActorID),
+					event.Action,
+					event.TargetType,
+					event.TargetID,
+					nullableString(event.RequestID),
+					nullableString(event.IP),
+					event.CreatedAt,
+				}, nil
+			},
+		),
+	)
+	return err
+}
+
+func (r *AuditRepository) FindAll(
+	ctx context.Context,
+	queries model.AuditQuery,
+) ([]model.AuditEvent, error) {
+	queries.TargetID = r.targetIndex(queries)
+	var query bytes.Buffer
+	query.WriteString(`
+    select
+      id,
+      actor_id,
+      action,
+      target_type,
+      target_id,
+      coalesce(request_id, ''),
+      coalesce(ip, ''),
+      created_at
+    from audit_events
+    where 1 = 1
+  `)
+	queryString, params := util.BuildQueryStringAndParams(
+		&query,
+		queries.BuildWhereClauses,
+		queries.BuildPagination,
+		queries.BuildOrderByClause,
+		false,
+	)
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		queryString,
+		params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make(
+		[]model.AuditEvent,
+		0,
+		util.PageSize(queries.Limit)+1,
+	)
+	for rows.Next() {
+		var (
+			event   model.AuditEvent
+			actorID *uuid.UUID
+		)
+		err := rows.Scan(
+			&event.ID,
+			&actorID,
+			&event.Action,
+			&event.TargetType,
+			&event.TargetID,
+			&event.RequestID,
+			&event.IP,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if actorID != nil {
+			event.ActorID = *actorID
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *AuditRepository) Count(
+	ctx context.Context,
+	queries model.AuditQuery,
+) (int, error) {
+	queries.TargetID = r.targetIndex(queries)
+	var query bytes.Buffer
+	query.WriteString(`
+    select count(*)
+    from audit_events
+    where 1 = 1
+  `)
+	queryString, params := util.BuildQueryStringAndParamsWithoutLimit(
+		&query,
+		queries.BuildFilterClauses,
+		func() []string {
+			return nil
+		},
+	)
+
+	var total int
+	err := conn(ctx, r.db).
+		QueryRow(
+			ctx,
+			queryString,
+			params...).
+		Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// targetIndex looks patients up by the blind index they are stored by.
+func (r *AuditRepository) targetIndex(
+	queries model.AuditQuery,
+) string {
+	if queries.TargetType != "" &&
+		queries.TargetType != constant.AuditTargetPatient {
+		return queries.TargetID
+	}
+	// no other target has 16 digit IDs
+	if util.ValidateIdentityNumber(queries.TargetID) != nil {
+		return queries.TargetID
+	}
+
+	return identityIndex(
+		r.keyring,
+		queries.TargetID,
+	)
+}
+
+// IndexPatientTargets disables the append-only trigger inside its transaction only.
+func (r *AuditRepository) IndexPatientTargets(
+	ctx context.Context,
+	batchSize int,
+) (int, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`
+    select distinct target_id
+    from audit_events
+    where target_type = $1 and
+      target_id ~ '^[0-9]{16}$'
+    limit $2
+  `,
+		constant.AuditTargetPatient,
+		batchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	identityNumbers, err := pgx.CollectRows(
+		rows,
+		pgx.RowTo[string],
+	)
+	if err != nil {
+		return 0, err
+	}
+	if len(identityNumbers) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`alter table audit_events disable trigger trg_audit_events_append_only`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, identityNumber := range identityNumbers {
+		tag, err := tx.Exec(
+			ctx,
+			`
+    update audit_events
+    set target_id = $1
+    where target_type = $2 and
+      target_id = $3
+  `,
+			identityIndex(
+				r.keyring,
+				identityNumber,
+			),
+			constant.AuditTargetPatient,
+			identityNumber,
+		)
+		if err != nil {
+			return 0, err
+		}
+		total += int(tag.RowsAffected())
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`alter table audit_events enable trigger trg_audit_events_append_only`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+func TestAuditAppendOnly(t *testing.T) {
+	pool := testPool(t)
+	auditRepository := NewAuditRepository(pool, testKeyring(t))
+	user := testUser(t, pool)
+	ctx := context.Background()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = auditRepository.Create(
+		ctx,
+		[]model.AuditEvent{
+			{
+				ID:         id,
+				ActorID:    user.ID,
+				Action:     constant.AuditActionRead,
+				TargetType: constant.AuditTargetUser,
+				TargetID:   user.ID.String(),
+				CreatedAt:  time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "update",
+			query: "update audit_events set action = 'delete' where id = $1",
+		},
+		{
+			name:  "delete",
+			query: "delete from audit_events where id = $1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := pool.Exec(ctx, test.query, id)
+			if err == nil {
+				t.Errorf("%s succeeded, want audit_events to reject it", test.name)
+			}
+		})
+	}
+}
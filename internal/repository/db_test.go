@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/db"
+	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/migration"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/pii"
+)
+
+// testPool connects to TEST_DATABASE_URL and migrates it, skipping the
+// test when it is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(
+		ctx,
+		url,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migration.New(
+		pool,
+		db.PrimaryMigrations,
+		db.PrimaryMigrationsDir,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+func testKeyring(t *testing.T) *pii.Keyring {
+	t.Helper()
+
+	key := func(b byte) string {
+		return base64.StdEncoding.EncodeToString(
+			bytes.Repeat([]byte{b}, 32),
+		)
+	}
+	keyring, err := pii.NewKeyring(
+		config.PIIConfig{
+			PIIKeys:     "1:" + key(1),
+			PIIIndexKey: key(9),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyring
+}
+
+func testUser(
+	t *testing.T,
+	pool *pgxpool.Pool,
+) model.User {
+	t.Helper()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentTime := time.Now()
+	user, err := NewUserRepository(pool).Save(
+		context.Background(),
+		model.User{
+			ID: id,
+			EmployeeID: fmt.Sprintf(
+				"3031202401%05d",
+				rand.IntN(100000),
+			),
+			Name:      "Repository Test",
+			Password:  "not-a-hash",
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func testPatient(
+	t *testing.T,
+	patientRepository *PatientRepository,
+	user model.User,
+) model.Patient {
+	t.Helper()
+
+	currentTime := time.Now()
+	patient, err := patientRepository.Create(
+		context.Background(),
+		model.Patient{
+			IdentityNumber: fmt.Sprintf(
+				"3171%012d",
+				rand.Int64N(1e12),
+			),
+			UserID:          user.ID,
+			UpdatedBy:       user.ID,
+			PhoneNumber:     "+6281234567890",
+			Name:            "Repository Patient",
+			Birthdate:       time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+			Gender:          "female",
+			IdentityScanImg: "https://example.com/id.png",
+			CreatedAt:       currentTime,
+			UpdatedAt:       currentTime,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return patient
+}
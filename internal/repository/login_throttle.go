@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type LoginThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginThrottleRepository(
+	db *pgxpool.Pool,
+) *LoginThrottleRepository {
+	return &LoginThrottleRepository{
+		db: db,
+	}
+}
+
+func (r *LoginThrottleRepository) FindLocked(
+	ctx context.Context,
+	keys []string,
+	now time.Time,
+) ([]model.LoginThrottle, error) {
+	query := `
+    select
+      key,
+      failed_attempts,
+      lockouts,
+      locked_until,
+      updated_at
+    from login_throttles
+    where key = any($1)
+      and locked_until > $2
+  `
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		query,
+		keys,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	throttles := make(
+		[]model.LoginThrottle,
+		0,
+		len(keys),
+	)
+	for rows.Next() {
+		var throttle model.LoginThrottle
+		err := rows.Scan(
+			&throttle.Key,
+			&throttle.FailedAttempts,
+			&throttle.Lockouts,
+			&throttle.LockedUntil,
+			&throttle.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		throttles = append(
+			throttles,
+			throttle,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return throttles, nil
+}
+
+// FindForUpdate creates the throttle of key when needed and locks its row.
+func (r *LoginThrottleRepository) FindForUpdate(
+	ctx context.Context,
+	key string,
+	now time.Time,
+) (model.LoginThrottle, error) {
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		`
+    insert into login_throttles
+    (
+      key,
+      updated_at
+    ) values (
+      $1, $2
+    )
+    on conflict (key) do nothing
+  `,
+		key,
+		now,
+	)
+	if err != nil {
+		return model.LoginThrottle{}, err
+	}
+
+	var (
+		throttle    model.LoginThrottle
+		lockedUntil *time.Time
+	)
+	err = conn(ctx, r.db).QueryRow(
+		ctx,
+		`
+    select
+      key,
+      failed_attempts,
+      lockouts,
+      locked_until,
+      updated_at
+    from login_throttles
+    where key = $1
+    for update
+  `,
+		key,
+	).Scan(
+		&throttle.Key,
+		&throttle.FailedAttempts,
+		&throttle.Lockouts,
+		&lockedUntil,
+		&throttle.UpdatedAt,
+	)
+	if err != nil {
+		return model.LoginThrottle{}, err
+	}
+	if lockedUntil != nil {
+		throttle.LockedUntil = *lockedUntil
+	}
+
+	return throttle, nil
+}
+
+func (r *LoginThrottleRepository) Save(
+	ctx context.Context,
+	throttle model.LoginThrottle,
+) (model.LoginThrottle, error) {
+	var lockedUntil *time.Time
+	if !throttle.LockedUntil.IsZero() {
+		lockedUntil = &throttle.LockedUntil
+	}
+
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		`
+    update login_throttles
+    set
+      failed_attempts = $1,
+      lockouts = $2,
+      locked_until = $3,
+      updated_at = $4
+    where key = $5
+  `,
+		throttle.FailedAttempts,
+		throttle.Lockouts,
+		lockedUntil,
+		throttle.UpdatedAt,
+		throttle.Key,
+	)
+	if err != nil {
+		return model.LoginThrottle{}, err
+	}
+
+	return throttle, nil
+}
+
+func (r *LoginThrottleRepository) Delete(
+	ctx context.Context,
+	key string,
+) error {
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		`
+    delete from login_throttles
+    where key = $1
+  `,
+		key,
+	)
+
+	return err
+}
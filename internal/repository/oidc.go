@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type OIDCRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOIDCRepository(
+	db *pgxpool.Pool,
+) *OIDCRepository {
+	return &OIDCRepository{
+		db: db,
+	}
+}
+
+func (r *OIDCRepository) SaveState(
+	ctx context.Context,
+	state model.OIDCState,
+) (model.OIDCState, error) {
+	query := `
+    insert into oidc_login_states
+    (
+      state_hash,
+      nonce,
+      code_verifier,
+      expires_at,
+      created_at
+    ) values (
+      $1, $2, $3, $4, $5
+    )
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		state.StateHash,
+		state.Nonce,
+		state.CodeVerifier,
+		state.ExpiresAt,
+		state.CreatedAt,
+	)
+	if err != nil {
+		return model.OIDCState{}, err
+	}
+
+	return state, nil
+}
+
+// TakeState fails with constant.ErrNotFound when the state is gone or expired.
+func (r *OIDCRepository) TakeState(
+	ctx context.Context,
+	stateHash string,
+	now time.Time,
+) (model.OIDCState, error) {
+	query := `
+    delete from oidc_login_states
+    where state_hash = $1
+    returning
+      nonce,
+      code_verifier,
+      expires_at,
+      created_at
+  `
+
+	state := model.OIDCState{
+		StateHash: stateHash,
+	}
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		stateHash,
+	).Scan(
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+		&state.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.OIDCState{}, constant.ErrNotFound
+		}
+		return model.OIDCState{}, err
+	}
+	if !now.Before(state.ExpiresAt) {
+		return model.OIDCState{}, constant.ErrNotFound
+	}
+
+	return state, nil
+}
+
+func (r *OIDCRepository) DeleteExpiredStates(
+	ctx context.Context,
+	now time.Time,
+) error {
+	query := `
+    delete from oidc_login_states
+    where expires_at <= $1
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		now,
+	)
+
+	return err
+}
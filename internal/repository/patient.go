@@ -6,20 +6,26 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/pii"
 	"github.com/nozzlium/halosuster/internal/util"
 )
 
+// PatientRepository keys patients by the blind index of their identity number.
 type PatientRepository struct {
-	db *pgx.Conn
+	db      *pgxpool.Pool
+	keyring *pii.Keyring
 }
 
 func NewPatientRepository(
-	db *pgx.Conn,
+	db *pgxpool.Pool,
+	keyring *pii.Keyring,
 ) *PatientRepository {
 	return &PatientRepository{
-		db: db,
+		db:      db,
+		keyring: keyring,
 	}
 }
 
@@ -32,9 +38,15 @@ func (r *PatientRepository) Create(
       (
         identity_number,
         user_id,
-        phone_number,
-        name,
-        birthdate,
+        updated_by,
+        identity_number_enc,
+        phone_number_enc,
+        name_enc,
+        birthdate_enc,
+        data_key,
+        key_version,
+        phone_number_index,
+        name_index,
         gender,
         identity_card_image_url,
         created_at,
@@ -42,23 +54,40 @@ func (r *PatientRepository) Create(
       )
     values 
       (
-        $1, $2, $3, $4, $5, $6, $7, $8, $9
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
       )
   `
-	_, err := r.db.Exec(
+	sealed, err := sealPatient(
+		r.keyring,
+		&patient,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+
+	_, err = conn(ctx, r.db).Exec(
 		ctx,
 		query,
-		patient.IdentityNumber,
+		patient.IdentityIndex,
 		patient.UserID,
-		patient.PhoneNumber,
-		patient.Name,
-		patient.Birthdate,
+		patient.UpdatedBy,
+		sealed.IdentityNumber,
+		sealed.PhoneNumber,
+		sealed.Name,
+		sealed.Birthdate,
+		sealed.DataKey,
+		sealed.KeyVersion,
+		sealed.PhoneNumberIndex,
+		sealed.NameIndex,
 		patient.Gender,
 		patient.IdentityScanImg,
 		patient.CreatedAt,
 		patient.UpdatedAt,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return model.Patient{}, constant.ErrConflict
+		}
 		return model.Patient{}, err
 	}
 
@@ -72,10 +101,16 @@ func (r *PatientRepository) FindById(
 	query := `
     select 
       identity_number,
-      phone_number,
-      name,
-      birthdate,
+      identity_number_enc,
+      phone_number_enc,
+      name_enc,
+      birthdate_enc,
+      data_key,
+      coalesce(key_version, 0),
       gender,
+      identity_card_image_url,
+      user_id,
+      updated_by,
       created_at
     from patients
     where identity_number = $1 and
@@ -83,13 +118,25 @@ func (r *PatientRepository) FindById(
   `
 
 	var patient model.Patient
-	err := r.db.QueryRow(ctx, query, id).
+	var sealed sealedPatient
+	err := conn(ctx, r.db).
+		QueryRow(
+			ctx,
+			query,
+			identityIndex(r.keyring, id),
+		).
 		Scan(
-			&patient.IdentityNumber,
-			&patient.PhoneNumber,
-			&patient.Name,
-			&patient.Birthdate,
+			&patient.IdentityIndex,
+			&sealed.IdentityNumber,
+			&sealed.PhoneNumber,
+			&sealed.Name,
+			&sealed.Birthdate,
+			&sealed.DataKey,
+			&sealed.KeyVersion,
 			&patient.Gender,
+			&patient.IdentityScanImg,
+			&patient.UserID,
+			&patient.UpdatedBy,
 			&patient.CreatedAt,
 		)
 	if err != nil {
@@ -102,6 +149,14 @@ func (r *PatientRepository) FindById(
 		return model.Patient{}, err
 	}
 
+	err = sealed.open(
+		r.keyring,
+		&patient,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+
 	return patient, nil
 }
 
@@ -113,14 +168,20 @@ func (r *PatientRepository) FindAll(
 	query.WriteString(`
     select 
       identity_number,
-      phone_number,
-      name,
-      birthdate,
+      identity_number_enc,
+      phone_number_enc,
+      name_enc,
+      birthdate_enc,
+      data_key,
+      coalesce(key_version, 0),
       gender,
+      user_id,
+      updated_by,
       created_at
     from patients
     where 1 = 1
   `)
+	queries.Index = r.queryIndex(queries)
 	queryString, params := util.BuildQueryStringAndParams(
 		&query,
 		queries.BuildWhereClauses,
@@ -128,7 +189,7 @@ func (r *PatientRepository) FindAll(
 		queries.BuildOrderByClause,
 		true,
 	)
-	rows, err := r.db.Query(
+	rows, err := conn(ctx, r.db).Query(
 		ctx,
 		queryString,
 		params...)
@@ -140,23 +201,37 @@ func (r *PatientRepository) FindAll(
 	patientData := make(
 		[]model.Patient,
 		0,
-		queries.Limit,
+		util.PageSize(queries.Limit)+1,
 	)
 	for rows.Next() {
 		var patient model.Patient
+		var sealed sealedPatient
 		err := rows.
 			Scan(
-				&patient.IdentityNumber,
-				&patient.PhoneNumber,
-				&patient.Name,
-				&patient.Birthdate,
+				&patient.IdentityIndex,
+				&sealed.IdentityNumber,
+				&sealed.PhoneNumber,
+				&sealed.Name,
+				&sealed.Birthdate,
+				&sealed.DataKey,
+				&sealed.KeyVersion,
 				&patient.Gender,
+				&patient.UserID,
+				&patient.UpdatedBy,
 				&patient.CreatedAt,
 			)
 		if err != nil {
 			return nil, err
 		}
 
+		err = sealed.open(
+			r.keyring,
+			&patient,
+		)
+		if err != nil {
+			return nil, err
+		}
+
 		patientData = append(
 			patientData,
 			patient,
@@ -165,3 +240,373 @@ func (r *PatientRepository) FindAll(
 
 	return patientData, nil
 }
+
+func (r *PatientRepository) Count(
+	ctx context.Context,
+	queries model.PatientQuery,
+) (int, error) {
+	var query bytes.Buffer
+	query.WriteString(`
+    select count(*)
+    from patients
+    where deleted_at is null
+  `)
+	queries.Index = r.queryIndex(queries)
+	queryString, params := util.BuildQueryStringAndParamsWithoutLimit(
+		&query,
+		queries.BuildFilterClauses,
+		func() []string {
+			return nil
+		},
+	)
+
+	var total int
+	err := conn(ctx, r.db).
+		QueryRow(
+			ctx,
+			queryString,
+			params...).
+		Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r *PatientRepository) Edit(
+	ctx context.Context,
+	patient model.Patient,
+) (model.Patient, error) {
+	query := `
+    update patients
+    set
+      identity_number_enc = $1,
+      phone_number_enc = $2,
+      name_enc = $3,
+      birthdate_enc = $4,
+      data_key = $5,
+      key_version = $6,
+      phone_number_index = $7,
+      name_index = $8,
+      gender = $9,
+      identity_card_image_url = $10,
+      updated_at = $11,
+      updated_by = $12
+    where identity_number = $13 and
+      deleted_at is null
+  `
+	// sealed again under a fresh data key
+	sealed, err := sealPatient(
+		r.keyring,
+		&patient,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		sealed.IdentityNumber,
+		sealed.PhoneNumber,
+		sealed.Name,
+		sealed.Birthdate,
+		sealed.DataKey,
+		sealed.KeyVersion,
+		sealed.PhoneNumberIndex,
+		sealed.NameIndex,
+		patient.Gender,
+		patient.IdentityScanImg,
+		patient.UpdatedAt,
+		patient.UpdatedBy,
+		patient.IdentityIndex,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.Patient{}, constant.ErrNotFound
+	}
+
+	return patient, nil
+}
+
+func (r *PatientRepository) SetDeletedAt(
+	ctx context.Context,
+	patient model.Patient,
+) (model.Patient, error) {
+	query := `
+    update patients
+    set
+      deleted_at = $1,
+      updated_at = $1,
+      updated_by = $2
+    where identity_number = $3 and
+      deleted_at is null
+  `
+	patient.IdentityIndex = identityIndex(
+		r.keyring,
+		patient.IdentityNumber,
+	)
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		patient.DeletedAt,
+		patient.UpdatedBy,
+		patient.IdentityIndex,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.Patient{}, constant.ErrNotFound
+	}
+
+	return patient, nil
+}
+
+func (r *PatientRepository) Restore(
+	ctx context.Context,
+	patient model.Patient,
+) (model.Patient, error) {
+	query := `
+    update patients
+    set
+      deleted_at = null,
+      updated_at = $1,
+      updated_by = $2
+    where identity_number = $3 and
+      deleted_at is not null
+  `
+	patient.IdentityIndex = identityIndex(
+		r.keyring,
+		patient.IdentityNumber,
+	)
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		patient.UpdatedAt,
+		patient.UpdatedBy,
+		patient.IdentityIndex,
+	)
+	if err != nil {
+		return model.Patient{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.Patient{}, constant.ErrNotFound
+	}
+
+	return patient, nil
+}
+
+func (r *PatientRepository) queryIndex(
+	queries model.PatientQuery,
+) model.PatientQueryIndex {
+	var index model.PatientQueryIndex
+	if queries.IdentityNumber != "" {
+		index.IdentityNumber = identityIndex(
+			r.keyring,
+			queries.IdentityNumber,
+		)
+	}
+	if queries.PhoneNumber != "" {
+		index.PhoneNumberPrefix = r.keyring.BlindIndex(
+			indexPhoneNumber,
+			"+"+queries.PhoneNumber,
+		)
+	}
+	if queries.Name != "" {
+		index.NameTokens = r.keyring.TokenIndex(
+			indexName,
+			queries.Name,
+		)
+	}
+
+	return index
+}
+
+// EncryptPlaintext returns how many patients it encrypted, zero once done.
+func (r *PatientRepository) EncryptPlaintext(
+	ctx context.Context,
+	batchSize int,
+) (int, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`
+    select
+      identity_number,
+      phone_number,
+      name,
+      birthdate
+    from patients
+    where key_version is null
+    limit $1
+    for update skip locked
+  `,
+		batchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	patients, err := pgx.CollectRows(
+		rows,
+		func(row pgx.CollectableRow) (model.Patient, error) {
+			var patient model.Patient
+			err := row.Scan(
+				&patient.IdentityNumber,
+				&patient.PhoneNumber,
+				&patient.Name,
+				&patient.Birthdate,
+			)
+			return patient, err
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, patient := range patients {
+		plaintextID := patient.IdentityNumber
+		sealed, err := sealPatient(
+			r.keyring,
+			&patient,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		// records follow the new key through ON UPDATE CASCADE
+		_, err = tx.Exec(
+			ctx,
+			`
+    update patients
+    set
+      identity_number = $1,
+      identity_number_enc = $2,
+      phone_number_enc = $3,
+      name_enc = $4,
+      birthdate_enc = $5,
+      data_key = $6,
+      key_version = $7,
+      phone_number_index = $8,
+      name_index = $9,
+      phone_number = null,
+      name = null,
+      birthdate = null
+    where identity_number = $10
+  `,
+			patient.IdentityIndex,
+			sealed.IdentityNumber,
+			sealed.PhoneNumber,
+			sealed.Name,
+			sealed.Birthdate,
+			sealed.DataKey,
+			sealed.KeyVersion,
+			sealed.PhoneNumberIndex,
+			sealed.NameIndex,
+			plaintextID,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(patients), nil
+}
+
+// RewrapDataKeys returns how many data keys it rewrapped, zero once done.
+func (r *PatientRepository) RewrapDataKeys(
+	ctx context.Context,
+	batchSize int,
+) (int, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	type wrappedKey struct {
+		identityIndex string
+		dataKey       []byte
+		keyVersion    int
+	}
+	rows, err := tx.Query(
+		ctx,
+		`
+    select
+      identity_number,
+      data_key,
+      key_version
+    from patients
+    where key_version <> $1
+    limit $2
+    for update skip locked
+  `,
+		r.keyring.ActiveVersion(),
+		batchSize,
+	)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := pgx.CollectRows(
+		rows,
+		func(row pgx.CollectableRow) (wrappedKey, error) {
+			var key wrappedKey
+			err := row.Scan(
+				&key.identityIndex,
+				&key.dataKey,
+				&key.keyVersion,
+			)
+			return key, err
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		dataKey, err := r.keyring.Rewrap(
+			key.dataKey,
+			key.keyVersion,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			`
+    update patients
+    set
+      data_key = $1,
+      key_version = $2
+    where identity_number = $3
+  `,
+			dataKey,
+			r.keyring.ActiveVersion(),
+			key.identityIndex,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
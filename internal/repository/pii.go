@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/pii"
+)
+
+// purposes keep the blind indexes of different columns apart
+const (
+	indexIdentityNumber = "patient.identity_number"
+	indexPhoneNumber    = "patient.phone_number"
+	indexName           = "patient.name"
+)
+
+var errPlaintextPatient = errors.New(
+	"patient is not encrypted yet, run `halosuster pii backfill`",
+)
+
+// sealedPatient binds every ciphertext to its column and identity index.
+type sealedPatient struct {
+	IdentityNumber   []byte
+	PhoneNumber      []byte
+	Name             []byte
+	Birthdate        []byte
+	DataKey          []byte
+	KeyVersion       int
+	PhoneNumberIndex []string
+	NameIndex        []string
+}
+
+func identityIndex(
+	keyring *pii.Keyring,
+	identityNumber string,
+) string {
+	return keyring.BlindIndex(
+		indexIdentityNumber,
+		identityNumber,
+	)
+}
+
+// sealPatient also sets the IdentityIndex of patient.
+func sealPatient(
+	keyring *pii.Keyring,
+	patient *model.Patient,
+) (sealedPatient, error) {
+	patient.IdentityIndex = identityIndex(
+		keyring,
+		patient.IdentityNumber,
+	)
+
+	envelope, err := keyring.NewEnvelope()
+	if err != nil {
+		return sealedPatient{}, err
+	}
+
+	sealed := sealedPatient{
+		DataKey:    envelope.WrappedKey,
+		KeyVersion: envelope.KeyVersion,
+		PhoneNumberIndex: keyring.PrefixIndex(
+			indexPhoneNumber,
+			patient.PhoneNumber,
+		),
+		NameIndex: keyring.TokenPrefixIndex(
+			indexName,
+			patient.Name,
+		),
+	}
+
+	fields := []struct {
+		column string
+		value  string
+		target *[]byte
+	}{
+		{"identity_number", patient.IdentityNumber, &sealed.IdentityNumber},
+		{"phone_number", patient.PhoneNumber, &sealed.PhoneNumber},
+		{"name", patient.Name, &sealed.Name},
+		{"birthdate", patient.Birthdate.Format(time.RFC3339Nano), &sealed.Birthdate},
+	}
+	for _, field := range fields {
+		*field.target, err = envelope.Seal(
+			field.value,
+			field.column+":"+patient.IdentityIndex,
+		)
+		if err != nil {
+			return sealedPatient{}, err
+		}
+	}
+
+	return sealed, nil
+}
+
+// open needs the IdentityIndex of patient to be set.
+func (sealed sealedPatient) open(
+	keyring *pii.Keyring,
+	patient *model.Patient,
+) error {
+	if sealed.KeyVersion == 0 {
+		return errPlaintextPatient
+	}
+
+	envelope, err := keyring.OpenEnvelope(
+		sealed.DataKey,
+		sealed.KeyVersion,
+	)
+	if err != nil {
+		return err
+	}
+
+	var birthdate string
+	fields := []struct {
+		column string
+		value  []byte
+		target *string
+	}{
+		{"identity_number", sealed.IdentityNumber, &patient.IdentityNumber},
+		{"phone_number", sealed.PhoneNumber, &patient.PhoneNumber},
+		{"name", sealed.Name, &patient.Name},
+		{"birthdate", sealed.Birthdate, &birthdate},
+	}
+	for _, field := range fields {
+		*field.target, err = envelope.Open(
+			field.value,
+			field.column+":"+patient.IdentityIndex,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	patient.Birthdate, err = time.Parse(
+		time.RFC3339Nano,
+		birthdate,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,27 +1,37 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"errors"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/pii"
+	"github.com/nozzlium/halosuster/internal/util"
 )
 
+// RecordRepository references patients by their blind index.
 type RecordRepository struct {
-	db *pgx.Conn
+	db      *pgxpool.Pool
+	keyring *pii.Keyring
 }
 
 func NewRecordRepository(
-	db *pgx.Conn,
+	db *pgxpool.Pool,
+	keyring *pii.Keyring,
 ) *RecordRepository {
 	return &RecordRepository{
-		db: db,
+		db:      db,
+		keyring: keyring,
 	}
 }
 
+// Create fails with constant.ErrNotFound when the patient is deleted.
 func (r *RecordRepository) Create(
 	ctx context.Context,
 	record model.Record,
@@ -30,20 +40,32 @@ func (r *RecordRepository) Create(
     insert into records
     (
       id,
+      record_id,
+      revision,
       identity_number,
       user_id,
+      created_by,
       symptomps,
       medications,
       created_at,
       updated_at
-    ) values (
-      $1, $2, $3, $4, $5, $6, $7
+    )
+    select
+      $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+    where exists (
+      select 1
+      from patients
+      where identity_number = $4
+        and deleted_at is null
     )
   `
-	_, err := r.db.Exec(ctx, query,
+	result, err := conn(ctx, r.db).Exec(ctx, query,
 		record.ID,
-		record.IdentityNumber,
+		record.RecordID,
+		record.Revision,
+		identityIndex(r.keyring, record.IdentityNumber),
 		record.UserID,
+		record.CreatedBy,
 		record.Symptomps,
 		record.Medications,
 		record.CreatedAt,
@@ -58,6 +80,348 @@ func (r *RecordRepository) Create(
 		}
 		return model.Record{}, err
 	}
+	if result.RowsAffected() == 0 {
+		return model.Record{}, constant.ErrNotFound
+	}
+
+	return record, nil
+}
+
+func (r *RecordRepository) FindAll(
+	ctx context.Context,
+	queries model.RecordQuery,
+) ([]model.Record, error) {
+	var query bytes.Buffer
+	query.WriteString(`
+    select
+      r.id,
+      r.record_id,
+      r.revision,
+      r.identity_number,
+      r.symptomps,
+      r.medications,
+      r.created_at,
+      p.identity_number_enc,
+      p.phone_number_enc,
+      p.name_enc,
+      p.birthdate_enc,
+      p.data_key,
+      coalesce(p.key_version, 0),
+      p.gender,
+      p.identity_card_image_url,
+      c.id,
+      c.employee_id,
+      c.name,
+      u.id,
+      u.employee_id,
+      u.name
+    from records r
+      join patients p on p.identity_number = r.identity_number
+      join users c on c.id = r.created_by
+      join users u on u.id = r.user_id
+    where r.deleted_at is null
+      and r.superseded_at is null
+      and p.deleted_at is null
+  `)
+	if queries.IdentityNumber != "" {
+		queries.IdentityIndex = identityIndex(
+			r.keyring,
+			queries.IdentityNumber,
+		)
+	}
+	queryString, params := util.BuildQueryStringAndParams(
+		&query,
+		queries.BuildWhereClauses,
+		queries.BuildPagination,
+		queries.BuildOrderByClause,
+		false,
+	)
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		queryString,
+		params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(
+		[]model.Record,
+		0,
+		util.PageSize(queries.Limit)+1,
+	)
+	for rows.Next() {
+		var record model.Record
+		var sealed sealedPatient
+		err := rows.Scan(
+			&record.ID,
+			&record.RecordID,
+			&record.Revision,
+			&record.Patient.IdentityIndex,
+			&record.Symptomps,
+			&record.Medications,
+			&record.CreatedAt,
+			&sealed.IdentityNumber,
+			&sealed.PhoneNumber,
+			&sealed.Name,
+			&sealed.Birthdate,
+			&sealed.DataKey,
+			&sealed.KeyVersion,
+			&record.Patient.Gender,
+			&record.Patient.IdentityScanImg,
+			&record.Creator.ID,
+			&record.Creator.EmployeeID,
+			&record.Creator.Name,
+			&record.User.ID,
+			&record.User.EmployeeID,
+			&record.User.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		err = sealed.open(
+			r.keyring,
+			&record.Patient,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.IdentityNumber = record.Patient.IdentityNumber
+		record.CreatedBy = record.Creator.ID
+		record.UserID = record.User.ID
+
+		records = append(
+			records,
+			record,
+		)
+	}
+
+	return records, nil
+}
+
+func (r *RecordRepository) Count(
+	ctx context.Context,
+	queries model.RecordQuery,
+) (int, error) {
+	var query bytes.Buffer
+	query.WriteString(`
+    select count(*)
+    from records r
+      join patients p on p.identity_number = r.identity_number
+      join users c on c.id = r.created_by
+    where r.deleted_at is null
+      and r.superseded_at is null
+      and p.deleted_at is null
+  `)
+	if queries.IdentityNumber != "" {
+		queries.IdentityIndex = identityIndex(
+			r.keyring,
+			queries.IdentityNumber,
+		)
+	}
+	queryString, params := util.BuildQueryStringAndParamsWithoutLimit(
+		&query,
+		queries.BuildFilterClauses,
+		func() []string {
+			return nil
+		},
+	)
+
+	var total int
+	err := conn(ctx, r.db).
+		QueryRow(
+			ctx,
+			queryString,
+			params...).
+		Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Amend supersedes the latest revision of record.RecordID with record.
+func (r *RecordRepository) Amend(
+	ctx context.Context,
+	record model.Record,
+) (model.Record, error) {
+	tx, err := conn(ctx, r.db).Begin(ctx)
+	if err != nil {
+		return model.Record{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// carried over from the previous revision
+	var previousID uuid.UUID
+	var patientIndex string
+	err = tx.QueryRow(
+		ctx,
+		`
+    select
+      r.id,
+      r.identity_number,
+      r.created_by,
+      r.created_at,
+      r.revision
+    from records r
+      join patients p on p.identity_number = r.identity_number
+    where r.record_id = $1
+      and r.superseded_at is null
+      and r.deleted_at is null
+      and p.deleted_at is null
+    for update of r
+  `,
+		record.RecordID,
+	).Scan(
+		&previousID,
+		&patientIndex,
+		&record.CreatedBy,
+		&record.CreatedAt,
+		&record.Revision,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.Record{}, constant.ErrNotFound
+		}
+		return model.Record{}, err
+	}
+	record.Revision++
+
+	_, err = tx.Exec(
+		ctx,
+		`
+    update records
+    set superseded_at = $1
+    where id = $2
+  `,
+		record.UpdatedAt,
+		previousID,
+	)
+	if err != nil {
+		return model.Record{}, err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`
+    insert into records
+    (
+      id,
+      record_id,
+      revision,
+      amend_reason,
+      identity_number,
+      user_id,
+      created_by,
+      symptomps,
+      medications,
+      created_at,
+      updated_at
+    ) values (
+      $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+    )
+  `,
+		record.ID,
+		record.RecordID,
+		record.Revision,
+		record.AmendReason,
+		patientIndex,
+		record.UserID,
+		record.CreatedBy,
+		record.Symptomps,
+		record.Medications,
+		record.CreatedAt,
+		record.UpdatedAt,
+	)
+	if err != nil {
+		return model.Record{}, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return model.Record{}, err
+	}
 
 	return record, nil
 }
+
+func (r *RecordRepository) FindRevisions(
+	ctx context.Context,
+	recordID uuid.UUID,
+) ([]model.Record, error) {
+	query := `
+    select
+      r.id,
+      r.record_id,
+      r.revision,
+      coalesce(r.amend_reason, ''),
+      r.symptomps,
+      r.medications,
+      r.created_at,
+      r.updated_at,
+      u.id,
+      u.employee_id,
+      u.name
+    from records r
+      join patients p on p.identity_number = r.identity_number
+      join users u on u.id = r.user_id
+    where r.record_id = $1
+      and r.deleted_at is null
+      and p.deleted_at is null
+    order by r.revision desc
+  `
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		query,
+		recordID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(
+		[]model.Record,
+		0,
+		1,
+	)
+	for rows.Next() {
+		var record model.Record
+		err := rows.Scan(
+			&record.ID,
+			&record.RecordID,
+			&record.Revision,
+			&record.AmendReason,
+			&record.Symptomps,
+			&record.Medications,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+			&record.User.ID,
+			&record.User.EmployeeID,
+			&record.User.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+		record.UserID = record.User.ID
+
+		records = append(
+			records,
+			record,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, constant.ErrNotFound
+	}
+
+	return records, nil
+}
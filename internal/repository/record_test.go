@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+func newTestRecord(
+	t *testing.T,
+	patient model.Patient,
+	user model.User,
+	createdAt time.Time,
+) model.Record {
+	t.Helper()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return model.Record{
+		ID:             id,
+		RecordID:       id,
+		Revision:       1,
+		IdentityNumber: patient.IdentityNumber,
+		UserID:         user.ID,
+		CreatedBy:      user.ID,
+		Symptomps:      "fever",
+		Medications:    "paracetamol",
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	}
+}
+
+func TestRecordDeletedPatient(t *testing.T) {
+	pool := testPool(t)
+	keyring := testKeyring(t)
+	patientRepository := NewPatientRepository(pool, keyring)
+	recordRepository := NewRecordRepository(pool, keyring)
+	user := testUser(t, pool)
+	patient := testPatient(t, patientRepository, user)
+	ctx := context.Background()
+
+	record, err := recordRepository.Create(
+		ctx,
+		newTestRecord(t, patient, user, time.Now()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patient.DeletedAt = time.Now()
+	patient.UpdatedBy = user.ID
+	_, err = patientRepository.SetDeletedAt(ctx, patient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = patientRepository.FindById(ctx, patient.IdentityNumber)
+	if !errors.Is(err, constant.ErrNotFound) {
+		t.Errorf("FindById = %v, want ErrNotFound", err)
+	}
+	_, err = recordRepository.Create(
+		ctx,
+		newTestRecord(t, patient, user, time.Now()),
+	)
+	if !errors.Is(err, constant.ErrNotFound) {
+		t.Errorf("Create = %v, want ErrNotFound", err)
+	}
+	amended := newTestRecord(t, patient, user, time.Now())
+	amended.RecordID = record.RecordID
+	amended.AmendReason = "typo"
+	_, err = recordRepository.Amend(ctx, amended)
+	if !errors.Is(err, constant.ErrNotFound) {
+		t.Errorf("Amend = %v, want ErrNotFound", err)
+	}
+	revisions, err := recordRepository.FindRevisions(ctx, record.RecordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("FindRevisions returned %d revisions, want none", len(revisions))
+	}
+
+	patient.UpdatedAt = time.Now()
+	_, err = patientRepository.Restore(ctx, patient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = recordRepository.Amend(ctx, amended)
+	if err != nil {
+		t.Errorf("Amend after restore = %v, want nil", err)
+	}
+	revisions, err = recordRepository.FindRevisions(ctx, record.RecordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Errorf("FindRevisions returned %d revisions, want 2", len(revisions))
+	}
+}
+
+func TestRecordKeysetPaging(t *testing.T) {
+	pool := testPool(t)
+	keyring := testKeyring(t)
+	recordRepository := NewRecordRepository(pool, keyring)
+	user := testUser(t, pool)
+	patient := testPatient(t, NewPatientRepository(pool, keyring), user)
+	ctx := context.Background()
+
+	// two records share a createdAt so the tiebreaker has to order them
+	start := time.Now().UTC().Truncate(time.Second)
+	createdAts := []time.Time{
+		start,
+		start.Add(time.Second),
+		start.Add(time.Second),
+		start.Add(2 * time.Second),
+		start.Add(3 * time.Second),
+	}
+	for _, createdAt := range createdAts {
+		_, err := recordRepository.Create(
+			ctx,
+			newTestRecord(t, patient, user, createdAt),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		seen   []model.Record
+		cursor string
+	)
+	for page := 0; page < len(createdAts); page++ {
+		list, err := util.ParseListQuery(
+			"",
+			map[string]string{"cursor": cursor},
+			model.RecordQueryFields,
+			model.RecordKeyset,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queries := model.RecordQuery{
+			IdentityNumber: patient.IdentityNumber,
+			CreatedAt:      string(model.Asc),
+			List:           list,
+			Limit:          2,
+		}
+		records, err := recordRepository.FindAll(ctx, queries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, hasMore := util.TrimPage(records, queries.Limit)
+		seen = append(seen, records...)
+		if !hasMore {
+			break
+		}
+		last := records[len(records)-1]
+		cursor = list.NextCursor(
+			model.RecordKeyset,
+			hasMore,
+			last.CreatedAt,
+			last.ID.String(),
+		)
+	}
+
+	if len(seen) != len(createdAts) {
+		t.Fatalf("paged through %d records, want %d", len(seen), len(createdAts))
+	}
+	ids := make(map[uuid.UUID]bool)
+	for i, record := range seen {
+		if ids[record.ID] {
+			t.Errorf("record %s repeated", record.ID)
+		}
+		ids[record.ID] = true
+		if !record.CreatedAt.Equal(createdAts[i]) {
+			t.Errorf("record %d createdAt = %v, want %v", i, record.CreatedAt, createdAts[i])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type RoleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRoleRepository(
+	db *pgxpool.Pool,
+) *RoleRepository {
+	return &RoleRepository{
+		db: db,
+	}
+}
+
+func (r *RoleRepository) FindAll(
+	ctx context.Context,
+) ([]model.Role, error) {
+	query := `
+    select
+      r.name,
+      array_remove(array_agg(rp.permission_name), null)
+    from roles r
+      left join role_permissions rp on rp.role_name = r.name
+    group by r.name
+  `
+	rows, err := conn(ctx, r.db).Query(
+		ctx,
+		query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(
+		[]model.Role,
+		0,
+		2,
+	)
+	for rows.Next() {
+		var role model.Role
+		err := rows.Scan(
+			&role.Name,
+			&role.Permissions,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RoleRepository) AssignRole(
+	ctx context.Context,
+	userID uuid.UUID,
+	roleName string,
+) error {
+	query := `
+    insert into user_roles
+    (
+      user_id,
+      role_name
+    ) values (
+      $1, $2
+    )
+    on conflict do nothing
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		userID,
+		roleName,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return constant.ErrNotFound
+			}
+		}
+		return err
+	}
+
+	return nil
+}
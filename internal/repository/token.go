@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type TokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(
+	db *pgxpool.Pool,
+) *TokenRepository {
+	return &TokenRepository{
+		db: db,
+	}
+}
+
+func (r *TokenRepository) SaveRefreshToken(
+	ctx context.Context,
+	token model.RefreshToken,
+) (model.RefreshToken, error) {
+	query := `
+    insert into refresh_tokens
+    (
+      id,
+      user_id,
+      family_id,
+      token_hash,
+      expires_at,
+      created_at
+    ) values (
+      $1, $2, $3, $4, $5, $6
+    )
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (r *TokenRepository) FindRefreshTokenByHash(
+	ctx context.Context,
+	tokenHash string,
+) (model.RefreshToken, error) {
+	query := `
+    select
+      id,
+      user_id,
+      family_id,
+      expires_at,
+      revoked_at
+    from refresh_tokens
+    where token_hash = $1
+  `
+
+	var (
+		token     model.RefreshToken
+		revokedAt *time.Time
+	)
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		tokenHash,
+	).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.RefreshToken{}, constant.ErrNotFound
+		}
+		return model.RefreshToken{}, err
+	}
+	if revokedAt != nil {
+		token.RevokedAt = *revokedAt
+	}
+	token.TokenHash = tokenHash
+
+	return token, nil
+}
+
+// RotateRefreshToken returns constant.ErrConflict when token is replayed.
+func (r *TokenRepository) RotateRefreshToken(
+	ctx context.Context,
+	token model.RefreshToken,
+) error {
+	query := `
+    update refresh_tokens
+    set
+      revoked_at = $1,
+      replaced_by = $2
+    where id = $3 and
+      revoked_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		token.RevokedAt,
+		token.ReplacedBy,
+		token.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return constant.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) RevokeRefreshTokenFamily(
+	ctx context.Context,
+	familyID uuid.UUID,
+	revokedAt time.Time,
+) error {
+	query := `
+    update refresh_tokens
+    set revoked_at = $1
+    where family_id = $2 and
+      revoked_at is null
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		revokedAt,
+		familyID,
+	)
+	return err
+}
+
+func (r *TokenRepository) RevokeUserRefreshTokens(
+	ctx context.Context,
+	userID uuid.UUID,
+	revokedAt time.Time,
+) error {
+	query := `
+    update refresh_tokens
+    set revoked_at = $1
+    where user_id = $2 and
+      revoked_at is null
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		revokedAt,
+		userID,
+	)
+	return err
+}
+
+func (r *TokenRepository) RevokeAccessToken(
+	ctx context.Context,
+	session model.Session,
+) error {
+	query := `
+    insert into revoked_access_tokens
+    (
+      jti,
+      expires_at
+    ) values (
+      $1, $2
+    )
+    on conflict do nothing
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		session.TokenID,
+		session.ExpiresAt,
+	)
+	return err
+}
+
+// UseChallenge returns constant.ErrConflict when the challenge was used.
+func (r *TokenRepository) UseChallenge(
+	ctx context.Context,
+	tokenID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	query := `
+    insert into revoked_access_tokens
+    (
+      jti,
+      expires_at
+    ) values (
+      $1, $2
+    )
+    on conflict do nothing
+  `
+	result, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		tokenID,
+		expiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return constant.ErrConflict
+	}
+
+	return nil
+}
+
+func (r *TokenRepository) DeleteExpiredAccessTokens(
+	ctx context.Context,
+	now time.Time,
+) error {
+	query := `
+    delete from revoked_access_tokens
+    where expires_at < $1
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		now,
+	)
+	return err
+}
+
+// IncrementTokenVersion invalidates every access token of the user.
+func (r *TokenRepository) IncrementTokenVersion(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	query := `
+    update users
+    set token_version = token_version + 1
+    where id = $1
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		userID,
+	)
+	return err
+}
+
+func (r *TokenRepository) IsSessionActive(
+	ctx context.Context,
+	session model.Session,
+) (bool, error) {
+	query := `
+    select
+      exists (
+        select 1
+        from users
+        where id = $1 and
+          token_version = $2 and
+          deleted_at is null
+      ) and not exists (
+        select 1
+        from revoked_access_tokens
+        where jti = $3
+      )
+  `
+
+	var active bool
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		session.UserID,
+		session.TokenVersion,
+		session.TokenID,
+	).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+)
+
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTwoFactorRepository(
+	db *pgxpool.Pool,
+) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+func (r *TwoFactorRepository) FindTOTP(
+	ctx context.Context,
+	userID uuid.UUID,
+) (model.TOTP, error) {
+	query := `
+    select
+      user_id,
+      secret,
+      enabled_at,
+      last_step,
+      created_at
+    from user_totp
+    where user_id = $1
+  `
+
+	var (
+		totp      model.TOTP
+		enabledAt *time.Time
+	)
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		userID,
+	).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&enabledAt,
+		&totp.LastStep,
+		&totp.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.TOTP{}, constant.ErrNotFound
+		}
+		return model.TOTP{}, err
+	}
+	if enabledAt != nil {
+		totp.EnabledAt = *enabledAt
+	}
+
+	return totp, nil
+}
+
+// SaveEnrollment fails with constant.ErrConflict once two-factor is enabled.
+func (r *TwoFactorRepository) SaveEnrollment(
+	ctx context.Context,
+	totp model.TOTP,
+) (model.TOTP, error) {
+	query := `
+    insert into user_totp
+    (
+      user_id,
+      secret,
+      created_at
+    ) values (
+      $1, $2, $3
+    )
+    on conflict (user_id) do update
+    set
+      secret = excluded.secret,
+      last_step = 0,
+      created_at = excluded.created_at
+    where user_totp.enabled_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		totp.UserID,
+		totp.Secret,
+		totp.CreatedAt,
+	)
+	if err != nil {
+		return model.TOTP{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.TOTP{}, constant.ErrConflict
+	}
+
+	return totp, nil
+}
+
+func (r *TwoFactorRepository) Enable(
+	ctx context.Context,
+	totp model.TOTP,
+) (model.TOTP, error) {
+	query := `
+    update user_totp
+    set
+      enabled_at = $1,
+      last_step = $2
+    where user_id = $3
+      and enabled_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		totp.EnabledAt,
+		totp.LastStep,
+		totp.UserID,
+	)
+	if err != nil {
+		return model.TOTP{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.TOTP{}, constant.ErrConflict
+	}
+
+	return totp, nil
+}
+
+// UseStep fails with constant.ErrBadInput for a step that is not newer.
+func (r *TwoFactorRepository) UseStep(
+	ctx context.Context,
+	userID uuid.UUID,
+	step int64,
+) error {
+	query := `
+    update user_totp
+    set last_step = $1
+    where user_id = $2
+      and last_step < $1
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		step,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return constant.ErrBadInput
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+	codes []model.RecoveryCode,
+) error {
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		`
+    delete from user_recovery_codes
+    where user_id = $1
+  `,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).CopyFrom(
+		ctx,
+		pgx.Identifier{"user_recovery_codes"},
+		[]string{
+			"id",
+			"user_id",
+			"code_hash",
+			"created_at",
+		},
+		pgx.CopyFromSlice(
+			len(codes),
+			func(i int) ([]any, error) {
+				return []any{
+					codes[i].ID,
+					codes[i].UserID,
+					codes[i].CodeHash,
+					codes[i].CreatedAt,
+				}, nil
+			},
+		),
+	)
+
+	return err
+}
+
+// UseRecoveryCode fails with constant.ErrNotFound when there is no unused code.
+func (r *TwoFactorRepository) UseRecoveryCode(
+	ctx context.Context,
+	userID uuid.UUID,
+	codeHash string,
+	usedAt time.Time,
+) error {
+	query := `
+    update user_recovery_codes
+    set used_at = $1
+    where user_id = $2
+      and code_hash = $3
+      and used_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		usedAt,
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return constant.ErrNotFound
+	}
+
+	return nil
+}
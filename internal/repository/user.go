@@ -4,20 +4,22 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/util"
 )
 
 type UserRepository struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
 func NewUserRepository(
-	db *pgx.Conn,
+	db *pgxpool.Pool,
 ) *UserRepository {
 	return &UserRepository{
 		db: db,
@@ -36,6 +38,9 @@ func (r *UserRepository) Save(
       name,
       password,
       identity_card_image_url,
+      has_access,
+      created_by,
+      updated_by,
       created_at,
       updated_at
     ) values (
@@ -45,21 +50,30 @@ func (r *UserRepository) Save(
       $4,
       $5,
       $6,
-      $7
+      $7,
+      $8,
+      $9,
+      $10
     )
   `
-	_, err := r.db.Exec(
+	_, err := conn(ctx, r.db).Exec(
 		ctx,
 		query,
 		user.ID,
 		user.EmployeeID,
 		user.Name,
-		user.Password,
+		nullableString(user.Password),
 		user.IdentityCardImageURL,
+		user.HasAccess,
+		nullableUUID(user.CreatedBy),
+		nullableUUID(user.UpdatedBy),
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return user, constant.ErrConflict
+		}
 		return user, err
 	}
 
@@ -75,7 +89,15 @@ func (r *UserRepository) FindById(
       id,
       name,
       employee_id,
-      password
+      coalesce(password, ''),
+      has_access,
+      token_version,
+      must_change_password,
+      array(
+        select ur.role_name
+        from user_roles ur
+        where ur.user_id = users.id
+      )
     from users
     where
       id = $1 and
@@ -83,11 +105,20 @@ func (r *UserRepository) FindById(
   `
 
 	var user model.User
-	err := r.db.QueryRow(
+	err := conn(ctx, r.db).QueryRow(
 		ctx,
 		query,
 		id,
-	).Scan(&user.ID, &user.Name, &user.EmployeeID, &user.Password)
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.EmployeeID,
+		&user.Password,
+		&user.HasAccess,
+		&user.TokenVersion,
+		&user.MustChangePassword,
+		&user.Roles,
+	)
 	if err != nil {
 		if errors.Is(
 			err,
@@ -111,19 +142,24 @@ func (r *UserRepository) FindAll(
       id,
       employee_id,
       name,
-      created_at
+      has_access,
+      created_by,
+      updated_by,
+      created_at,
+      deleted_at
       from users
     where 1 = 1
   `)
+	// SearchUserQuery picks deleted or active users itself
 	queryString, params := util.BuildQueryStringAndParams(
 		&query,
 		searchQuery.BuildWhereClauses,
 		searchQuery.BuildPagination,
 		searchQuery.BuildOrderByClause,
-		true,
+		false,
 	)
 
-	rows, err := r.db.Query(
+	rows, err := conn(ctx, r.db).Query(
 		ctx,
 		queryString,
 		params...)
@@ -135,19 +171,37 @@ func (r *UserRepository) FindAll(
 	users := make(
 		[]model.User,
 		0,
-		searchQuery.Limit,
+		util.PageSize(searchQuery.Limit)+1,
 	)
 	for rows.Next() {
-		var user model.User
+		var (
+			user      model.User
+			createdBy *uuid.UUID
+			updatedBy *uuid.UUID
+			deletedAt *time.Time
+		)
 		err := rows.Scan(
 			&user.ID,
 			&user.EmployeeID,
 			&user.Name,
+			&user.HasAccess,
+			&createdBy,
+			&updatedBy,
 			&user.CreatedAt,
+			&deletedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if createdBy != nil {
+			user.CreatedBy = *createdBy
+		}
+		if updatedBy != nil {
+			user.UpdatedBy = *updatedBy
+		}
+		if deletedAt != nil {
+			user.DeletedAt = *deletedAt
+		}
 
 		users = append(users, user)
 	}
@@ -155,6 +209,38 @@ func (r *UserRepository) FindAll(
 	return users, nil
 }
 
+func (r *UserRepository) Count(
+	ctx context.Context,
+	queries model.SearchUserQuery,
+) (int, error) {
+	var query bytes.Buffer
+	query.WriteString(`
+    select count(*)
+    from users
+    where 1 = 1
+  `)
+	queryString, params := util.BuildQueryStringAndParamsWithoutLimit(
+		&query,
+		queries.BuildFilterClauses,
+		func() []string {
+			return nil
+		},
+	)
+
+	var total int
+	err := conn(ctx, r.db).
+		QueryRow(
+			ctx,
+			queryString,
+			params...).
+		Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
 func (r *UserRepository) FindByEmployeeId(
 	ctx context.Context,
 	employeeId string,
@@ -164,7 +250,15 @@ func (r *UserRepository) FindByEmployeeId(
       id,
       name,
       employee_id,
-      password
+      coalesce(password, ''),
+      has_access,
+      token_version,
+      must_change_password,
+      array(
+        select ur.role_name
+        from user_roles ur
+        where ur.user_id = users.id
+      )
     from users
     where
       employee_id = $1 and
@@ -172,11 +266,20 @@ func (r *UserRepository) FindByEmployeeId(
   `
 
 	var user model.User
-	err := r.db.QueryRow(
+	err := conn(ctx, r.db).QueryRow(
 		ctx,
 		query,
 		employeeId,
-	).Scan(&user.ID, &user.Name, &user.EmployeeID, &user.Password)
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.EmployeeID,
+		&user.Password,
+		&user.HasAccess,
+		&user.TokenVersion,
+		&user.MustChangePassword,
+		&user.Roles,
+	)
 	if err != nil {
 		if errors.Is(
 			err,
@@ -190,19 +293,29 @@ func (r *UserRepository) FindByEmployeeId(
 	return user, nil
 }
 
+// EditPassword also sets whether the user has access at all.
 func (r *UserRepository) EditPassword(
 	ctx context.Context,
 	user model.User,
 ) (model.User, error) {
 	query := `
     update users
-    set password = $1
-    where id = $2
+    set
+      password = $1,
+      must_change_password = $2,
+      has_access = $3,
+      updated_at = $4,
+      updated_by = $5
+    where id = $6
   `
-	_, err := r.db.Exec(
+	_, err := conn(ctx, r.db).Exec(
 		ctx,
 		query,
-		user.Password,
+		nullableString(user.Password),
+		user.MustChangePassword,
+		user.HasAccess,
+		user.UpdatedAt,
+		nullableUUID(user.UpdatedBy),
 		user.ID,
 	)
 	if err != nil {
@@ -218,41 +331,368 @@ func (r *UserRepository) Edit(
 ) (model.User, error) {
 	query := `
     update users
-    set employee_id = $1, name = $2
-    where id = $3
+    set
+      employee_id = $1,
+      name = $2,
+      updated_at = $3,
+      updated_by = $4
+    where id = $5
   `
-	_, err := r.db.Exec(
+	_, err := conn(ctx, r.db).Exec(
 		ctx,
 		query,
 		user.EmployeeID,
 		user.Name,
+		user.UpdatedAt,
+		nullableUUID(user.UpdatedBy),
 		user.ID,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return model.User{}, constant.ErrConflict
+		}
 		return model.User{}, err
 	}
 
 	return user, nil
 }
 
+// SetDeletedAt also takes away the access and password of the user.
 func (r *UserRepository) SetDeletedAt(
 	ctx context.Context,
 	user model.User,
 ) (model.User, error) {
 	query := `
     update users
-    set deleted_at = $1
-    where id = $2
+    set
+      password = null,
+      has_access = false,
+      deleted_at = $1,
+      deleted_by = $2,
+      updated_at = $1,
+      updated_by = $2
+    where id = $3 and
+      deleted_at is null
   `
-	_, err := r.db.Exec(
+	tag, err := conn(ctx, r.db).Exec(
 		ctx,
 		query,
 		user.DeletedAt,
+		nullableUUID(user.DeletedBy),
+		user.ID,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.User{}, constant.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) FindDeletedById(
+	ctx context.Context,
+	id uuid.UUID,
+) (model.User, error) {
+	query := `
+    select
+      id,
+      name,
+      employee_id,
+      array(
+        select ur.role_name
+        from user_roles ur
+        where ur.user_id = users.id
+      ),
+      deleted_at
+    from users
+    where
+      id = $1 and
+      deleted_at is not null and
+      purged_at is null;
+  `
+
+	var user model.User
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.EmployeeID,
+		&user.Roles,
+		&user.DeletedAt,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.User{}, constant.ErrNotFound
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// Restore fails with constant.ErrConflict when the NIP was taken meanwhile.
+func (r *UserRepository) Restore(
+	ctx context.Context,
+	user model.User,
+) (model.User, error) {
+	query := `
+    update users
+    set
+      deleted_at = null,
+      deleted_by = null,
+      updated_at = $1,
+      updated_by = $2
+    where id = $3 and
+      deleted_at is not null and
+      purged_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		user.UpdatedAt,
+		nullableUUID(user.UpdatedBy),
 		user.ID,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return model.User{}, constant.ErrConflict
+		}
 		return model.User{}, err
 	}
+	if tag.RowsAffected() == 0 {
+		return model.User{}, constant.ErrNotFound
+	}
 
 	return user, nil
 }
+
+// HasAuthored counts every revision, superseded or not.
+func (r *UserRepository) HasAuthored(
+	ctx context.Context,
+	id uuid.UUID,
+) (bool, error) {
+	query := `
+    select
+      exists (
+        select 1 from patients
+        where user_id = $1 or updated_by = $1
+      ) or exists (
+        select 1 from records
+        where user_id = $1 or created_by = $1
+      )
+  `
+
+	var authored bool
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		id,
+	).Scan(&authored)
+	if err != nil {
+		return false, err
+	}
+
+	return authored, nil
+}
+
+func (r *UserRepository) ReassignAuthored(
+	ctx context.Context,
+	from uuid.UUID,
+	to uuid.UUID,
+) error {
+	for _, query := range []string{
+		`update patients set user_id = $2 where user_id = $1`,
+		`update patients set updated_by = $2 where updated_by = $1`,
+		`update records set user_id = $2 where user_id = $1`,
+		`update records set created_by = $2 where created_by = $1`,
+	} {
+		_, err := conn(ctx, r.db).Exec(
+			ctx,
+			query,
+			from,
+			to,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Anonymize keeps the row of a purged author with NIP 0 and no name.
+func (r *UserRepository) Anonymize(
+	ctx context.Context,
+	user model.User,
+) error {
+	query := `
+    update users
+    set
+      employee_id = '0',
+      name = '',
+      password = null,
+      has_access = false,
+      identity_card_image_url = null,
+      purged_at = $1,
+      updated_at = $1,
+      updated_by = $2
+    where id = $3 and
+      deleted_at is not null and
+      purged_at is null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		user.UpdatedAt,
+		nullableUUID(user.UpdatedBy),
+		user.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return constant.ErrNotFound
+	}
+
+	return nil
+}
+
+// Purge also removes the roles, tokens and second factor of the user.
+func (r *UserRepository) Purge(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	query := `
+    delete from users
+    where id = $1 and
+      deleted_at is not null
+  `
+	tag, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return constant.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) FindByIdentity(
+	ctx context.Context,
+	issuer string,
+	subject string,
+) (model.User, error) {
+	query := `
+    select
+      users.id,
+      users.name,
+      users.employee_id,
+      coalesce(users.password, ''),
+      users.has_access,
+      users.token_version,
+      users.must_change_password,
+      array(
+        select ur.role_name
+        from user_roles ur
+        where ur.user_id = users.id
+      )
+    from user_identities ui
+    join users on users.id = ui.user_id
+    where
+      ui.issuer = $1 and
+      ui.subject = $2 and
+      users.deleted_at is null;
+  `
+
+	var user model.User
+	err := conn(ctx, r.db).QueryRow(
+		ctx,
+		query,
+		issuer,
+		subject,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.EmployeeID,
+		&user.Password,
+		&user.HasAccess,
+		&user.TokenVersion,
+		&user.MustChangePassword,
+		&user.Roles,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			pgx.ErrNoRows,
+		) {
+			return model.User{}, constant.ErrNotFound
+		}
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// LinkIdentity fails with constant.ErrConflict when either side is already linked.
+func (r *UserRepository) LinkIdentity(
+	ctx context.Context,
+	identity model.UserIdentity,
+) (model.UserIdentity, error) {
+	query := `
+    insert into user_identities
+    (
+      issuer,
+      subject,
+      user_id,
+      created_at
+    ) values (
+      $1, $2, $3, $4
+    )
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		identity.Issuer,
+		identity.Subject,
+		identity.UserID,
+		identity.CreatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.UserIdentity{}, constant.ErrConflict
+		}
+		return model.UserIdentity{}, err
+	}
+
+	return identity, nil
+}
+
+func (r *UserRepository) UnlinkIdentities(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	query := `
+    delete from user_identities
+    where user_id = $1
+  `
+	_, err := conn(ctx, r.db).Exec(
+		ctx,
+		query,
+		userID,
+	)
+
+	return err
+}
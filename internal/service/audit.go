@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/repository"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+type AuditService struct {
+	auditRepository *repository.AuditRepository
+}
+
+func NewAuditService(
+	auditRepository *repository.AuditRepository,
+) *AuditService {
+	return &AuditService{
+		auditRepository: auditRepository,
+	}
+}
+
+// Record fills the actor, request ID and IP of events in from ctx.
+func (s *AuditService) Record(
+	ctx context.Context,
+	events ...model.AuditEvent,
+) error {
+	request := auth.RequestFrom(ctx)
+
+	currentTime := time.Now()
+	for i := range events {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		events[i].ID = id
+		if events[i].ActorID == uuid.Nil {
+			events[i].ActorID, err = auth.UserID(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		events[i].RequestID = request.ID
+		events[i].IP = request.IP
+		events[i].CreatedAt = currentTime
+	}
+
+	return s.auditRepository.Create(
+		ctx,
+		events,
+	)
+}
+
+func (s *AuditService) FindAll(
+	ctx context.Context,
+	queries model.AuditQuery,
+) ([]model.AuditEventResponseBody, model.PageMeta, error) {
+	events, err := s.auditRepository.FindAll(
+		ctx,
+		queries,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	meta, err := s.pageMeta(
+		ctx,
+		queries,
+		&events,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	eventData := make(
+		[]model.AuditEventResponseBody,
+		0,
+		len(events),
+	)
+	for _, event := range events {
+		eventData = append(
+			eventData,
+			event.ToResponseBody(),
+		)
+	}
+
+	return eventData, meta, nil
+}
+
+func (s *AuditService) pageMeta(
+	ctx context.Context,
+	queries model.AuditQuery,
+	events *[]model.AuditEvent,
+) (model.PageMeta, error) {
+	var (
+		meta    model.PageMeta
+		hasMore bool
+	)
+	*events, hasMore = util.TrimPage(
+		*events,
+		queries.Limit,
+	)
+	if len(*events) > 0 {
+		last := (*events)[len(*events)-1]
+		meta.NextCursor = queries.List.NextCursor(
+			model.AuditKeyset,
+			hasMore,
+			last.CreatedAt,
+			last.ID.String(),
+		)
+	}
+
+	if queries.List.WithTotal {
+		total, err := s.auditRepository.Count(
+			ctx,
+			queries,
+		)
+		if err != nil {
+			return model.PageMeta{}, err
+		}
+		meta.Total = &total
+	}
+
+	return meta, nil
+}
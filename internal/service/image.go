@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/storage"
+)
+
+type ImageService struct {
+	storage storage.Storage
+	maxSize int64
+}
+
+func NewImageService(
+	storage storage.Storage,
+	maxSize int64,
+) *ImageService {
+	return &ImageService{
+		storage: storage,
+		maxSize: maxSize,
+	}
+}
+
+// Upload returns the URL of the stored identity card scan.
+func (s *ImageService) Upload(
+	ctx context.Context,
+	image model.ImageUpload,
+) (model.ImageUploadResponseBody, error) {
+	contentType, extension, err := image.IsValid(
+		s.maxSize,
+	)
+	if err != nil {
+		return model.ImageUploadResponseBody{}, err
+	}
+
+	// util.ValidateURL rejects dashes before the extension
+	name := uuid.New()
+	imageURL, err := s.storage.Put(
+		ctx,
+		"images/"+hex.EncodeToString(name[:])+"."+extension,
+		contentType,
+		image.Content,
+	)
+	if err != nil {
+		return model.ImageUploadResponseBody{}, err
+	}
+
+	return model.ImageUploadResponseBody{
+		ImageURL: imageURL,
+	}, nil
+}
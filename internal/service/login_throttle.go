@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/nozzlium/halosuster/internal/auth"
+	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/repository"
+)
+
+// LoginThrottleService locks NIPs and blocks IPs, doubling every lockout.
+type LoginThrottleService struct {
+	transactor         *repository.Transactor
+	throttleRepository *repository.LoginThrottleRepository
+	cfg                config.LoginThrottleConfig
+}
+
+func NewLoginThrottleService(
+	transactor *repository.Transactor,
+	throttleRepository *repository.LoginThrottleRepository,
+	cfg config.LoginThrottleConfig,
+) *LoginThrottleService {
+	return &LoginThrottleService{
+		transactor:         transactor,
+		throttleRepository: throttleRepository,
+		cfg:                cfg,
+	}
+}
+
+// Check returns a *model.LoginThrottleError while the NIP or IP is locked.
+func (s *LoginThrottleService) Check(
+	ctx context.Context,
+	employeeID string,
+) error {
+	return s.check(
+		ctx,
+		employeeID,
+		s.keys(ctx, employeeID),
+	)
+}
+
+// CheckIP is Check for the client IP alone.
+func (s *LoginThrottleService) CheckIP(
+	ctx context.Context,
+) error {
+	return s.check(
+		ctx,
+		"",
+		ipKeys(ctx),
+	)
+}
+
+func (s *LoginThrottleService) check(
+	ctx context.Context,
+	employeeID string,
+	keys []string,
+) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	throttles, err := s.throttleRepository.FindLocked(
+		ctx,
+		keys,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	// a locked account wins over a blocked IP, it says more about why
+	var throttleErr *model.LoginThrottleError
+	for _, throttle := range throttles {
+		current := &model.LoginThrottleError{
+			Err:        constant.ErrTooManyAttempts,
+			RetryAfter: throttle.LockedUntil.Sub(now),
+		}
+		if throttle.Key == accountKey(employeeID) {
+			current.Err = constant.ErrAccountLocked
+		}
+		if throttleErr == nil ||
+			current.Err == constant.ErrAccountLocked {
+			throttleErr = current
+		}
+	}
+	if throttleErr != nil {
+		return throttleErr
+	}
+
+	return nil
+}
+
+func (s *LoginThrottleService) Fail(
+	ctx context.Context,
+	employeeID string,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			now := time.Now()
+			for _, key := range s.keys(ctx, employeeID) {
+				maxAttempts := s.cfg.LoginIPMaxAttempts
+				if key == accountKey(employeeID) {
+					maxAttempts = s.cfg.LoginMaxAttempts
+				}
+
+				err := s.fail(
+					ctx,
+					key,
+					maxAttempts,
+					now,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	)
+}
+
+func (s *LoginThrottleService) fail(
+	ctx context.Context,
+	key string,
+	maxAttempts int,
+	now time.Time,
+) error {
+	throttle, err := s.throttleRepository.FindForUpdate(
+		ctx,
+		key,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+
+	windowStart := now.Add(-s.cfg.LoginAttemptWindow)
+	if throttle.UpdatedAt.Before(windowStart) {
+		throttle.FailedAttempts = 0
+	}
+	if throttle.LockedUntil.Before(windowStart) {
+		throttle.Lockouts = 0
+	}
+
+	throttle.FailedAttempts++
+	throttle.UpdatedAt = now
+	if throttle.FailedAttempts >= maxAttempts {
+		throttle.LockedUntil = now.Add(
+			s.lockout(throttle.Lockouts),
+		)
+		throttle.Lockouts++
+		throttle.FailedAttempts = 0
+	}
+
+	_, err = s.throttleRepository.Save(
+		ctx,
+		throttle,
+	)
+
+	return err
+}
+
+// Reset leaves the IP counters alone so one account cannot unblock an IP.
+func (s *LoginThrottleService) Reset(
+	ctx context.Context,
+	employeeID string,
+) error {
+	return s.throttleRepository.Delete(
+		ctx,
+		accountKey(employeeID),
+	)
+}
+
+func (s *LoginThrottleService) lockout(lockouts int) time.Duration {
+	lockout := s.cfg.LoginLockout
+	for i := 0; i < lockouts && lockout < s.cfg.LoginLockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > s.cfg.LoginLockoutMax {
+		lockout = s.cfg.LoginLockoutMax
+	}
+
+	return lockout
+}
+
+func (s *LoginThrottleService) keys(
+	ctx context.Context,
+	employeeID string,
+) []string {
+	return append(
+		[]string{accountKey(employeeID)},
+		ipKeys(ctx)...,
+	)
+}
+
+// ipKeys is empty outside of HTTP requests.
+func ipKeys(ctx context.Context) []string {
+	if ip := auth.RequestFrom(ctx).IP; ip != "" {
+		return []string{"ip:" + ip}
+	}
+
+	return nil
+}
+
+func accountKey(employeeID string) string {
+	return "nip:" + employeeID
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+func TestLoginThrottleLockout(t *testing.T) {
+	throttleService := &LoginThrottleService{
+		cfg: config.LoginThrottleConfig{
+			LoginLockout:    time.Minute,
+			LoginLockoutMax: time.Hour,
+		},
+	}
+
+	tests := []struct {
+		lockouts int
+		want     time.Duration
+	}{
+		{lockouts: 0, want: time.Minute},
+		{lockouts: 1, want: 2 * time.Minute},
+		{lockouts: 2, want: 4 * time.Minute},
+		{lockouts: 5, want: 32 * time.Minute},
+		{lockouts: 6, want: time.Hour},
+		{lockouts: 100, want: time.Hour},
+	}
+
+	for _, test := range tests {
+		got := throttleService.lockout(test.lockouts)
+		if got != test.want {
+			t.Errorf("lockout(%d) = %v, want %v", test.lockouts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/oidc"
+	"github.com/nozzlium/halosuster/internal/repository"
+	"github.com/nozzlium/halosuster/internal/util"
+)
+
+// maxNameLength is the length of users.name
+const maxNameLength = 50
+
+// OIDCService links a directory account to a user by NIP on its first sign-in.
+type OIDCService struct {
+	transactor      *repository.Transactor
+	oidcRepository  *repository.OIDCRepository
+	userRepository  *repository.UserRepository
+	roleRepository  *repository.RoleRepository
+	auditService    *AuditService
+	userService     *UserService
+	provider        *oidc.Provider
+	issuer          string
+	nipClaim        string
+	nameClaim       string
+	provisionNurses bool
+	stateTTL        time.Duration
+}
+
+func NewOIDCService(
+	transactor *repository.Transactor,
+	oidcRepository *repository.OIDCRepository,
+	userRepository *repository.UserRepository,
+	roleRepository *repository.RoleRepository,
+	auditService *AuditService,
+	userService *UserService,
+	provider *oidc.Provider,
+	cfg config.OIDCConfig,
+) *OIDCService {
+	return &OIDCService{
+		transactor:      transactor,
+		oidcRepository:  oidcRepository,
+		userRepository:  userRepository,
+		roleRepository:  roleRepository,
+		auditService:    auditService,
+		userService:     userService,
+		provider:        provider,
+		issuer:          strings.TrimRight(cfg.OIDCIssuer, "/"),
+		nipClaim:        cfg.OIDCNIPClaim,
+		nameClaim:       cfg.OIDCNameClaim,
+		provisionNurses: cfg.OIDCProvisionNurses,
+		stateTTL:        cfg.OIDCStateTTL,
+	}
+}
+
+func (s *OIDCService) Start(
+	ctx context.Context,
+) (string, error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	currentTime := time.Now()
+	err = s.oidcRepository.DeleteExpiredStates(
+		ctx,
+		currentTime,
+	)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.oidcRepository.SaveState(
+		ctx,
+		model.OIDCState{
+			StateHash:    hashOIDCState(state),
+			Nonce:        nonce,
+			CodeVerifier: codeVerifier,
+			ExpiresAt:    currentTime.Add(s.stateTTL),
+			CreatedAt:    currentTime,
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return s.provider.AuthCodeURL(
+		ctx,
+		state,
+		nonce,
+		codeVerifier,
+	)
+}
+
+// Callback hands IT users with two-factor a challenge token, like Login.
+func (s *OIDCService) Callback(
+	ctx context.Context,
+	query model.OIDCCallbackQuery,
+) (model.UserRegisterResponseBody, error) {
+	state, err := s.oidcRepository.TakeState(
+		ctx,
+		hashOIDCState(query.State),
+		time.Now(),
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return model.UserRegisterResponseBody{}, constant.ErrUnauthorized
+		}
+		return model.UserRegisterResponseBody{}, err
+	}
+
+	claims, err := s.provider.Exchange(
+		ctx,
+		query.Code,
+		state.CodeVerifier,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			oidc.ErrRejected,
+		) {
+			return model.UserRegisterResponseBody{}, fmt.Errorf(
+				"%w: %v",
+				constant.ErrUnauthorized,
+				err,
+			)
+		}
+		return model.UserRegisterResponseBody{}, err
+	}
+	if claims["nonce"] != state.Nonce {
+		return model.UserRegisterResponseBody{}, fmt.Errorf(
+			"%w: ID token nonce does not match",
+			constant.ErrUnauthorized,
+		)
+	}
+
+	externalUser, err := s.externalUser(claims)
+	if err != nil {
+		return model.UserRegisterResponseBody{}, err
+	}
+
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.UserRegisterResponseBody, error) {
+			user, err := s.resolve(
+				ctx,
+				externalUser,
+			)
+			if err != nil {
+				return model.UserRegisterResponseBody{}, err
+			}
+
+			return s.userService.completeLogin(
+				ctx,
+				user,
+			)
+		},
+	)
+}
+
+// externalUser accepts the NIP as a string or a number.
+func (s *OIDCService) externalUser(
+	claims jwt.MapClaims,
+) (model.ExternalUser, error) {
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return model.ExternalUser{}, constant.ErrUnauthorized
+	}
+
+	var employeeID string
+	switch nip := claims[s.nipClaim].(type) {
+	case string:
+		employeeID = nip
+	case float64:
+		employeeID = strconv.FormatFloat(nip, 'f', -1, 64)
+	}
+	if util.ValidateGeneralEmployeeID(employeeID) != nil {
+		return model.ExternalUser{}, fmt.Errorf(
+			"%w: claim %q holds no valid NIP",
+			constant.ErrUnauthorized,
+			s.nipClaim,
+		)
+	}
+
+	name, _ := claims[s.nameClaim].(string)
+	name = strings.TrimSpace(name)
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	return model.ExternalUser{
+		Issuer:     s.issuer,
+		Subject:    subject,
+		EmployeeID: employeeID,
+		Name:       name,
+	}, nil
+}
+
+// resolve requires access like a password login does.
+func (s *OIDCService) resolve(
+	ctx context.Context,
+	externalUser model.ExternalUser,
+) (model.User, error) {
+	user, err := s.userRepository.FindByIdentity(
+		ctx,
+		externalUser.Issuer,
+		externalUser.Subject,
+	)
+	if err == nil {
+		return user, checkAccess(user)
+	}
+	if !errors.Is(
+		err,
+		constant.ErrNotFound,
+	) {
+		return model.User{}, err
+	}
+
+	user, err = s.userRepository.FindByEmployeeId(
+		ctx,
+		externalUser.EmployeeID,
+	)
+	switch {
+	case err == nil:
+		err = checkAccess(user)
+		if err != nil {
+			return model.User{}, err
+		}
+	case errors.Is(err, constant.ErrNotFound):
+		user, err = s.provisionNurse(
+			ctx,
+			externalUser,
+		)
+		if err != nil {
+			return model.User{}, err
+		}
+	default:
+		return model.User{}, err
+	}
+
+	_, err = s.userRepository.LinkIdentity(
+		ctx,
+		model.UserIdentity{
+			Issuer:    externalUser.Issuer,
+			Subject:   externalUser.Subject,
+			UserID:    user.ID,
+			CreatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrConflict,
+		) {
+			return model.User{}, fmt.Errorf(
+				"%w: user %s is linked to another directory account",
+				constant.ErrUnauthorized,
+				user.ID,
+			)
+		}
+		return model.User{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			ActorID:    user.ID,
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   user.ID.String(),
+		},
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// provisionNurse registers a nurse who is their own creator.
+func (s *OIDCService) provisionNurse(
+	ctx context.Context,
+	externalUser model.ExternalUser,
+) (model.User, error) {
+	if !s.provisionNurses {
+		return model.User{}, fmt.Errorf(
+			"%w: no user with NIP %s",
+			constant.ErrUnauthorized,
+			externalUser.EmployeeID,
+		)
+	}
+	if externalUser.Name == "" {
+		return model.User{}, fmt.Errorf(
+			"%w: claim %q holds no name",
+			constant.ErrUnauthorized,
+			s.nameClaim,
+		)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return model.User{}, err
+	}
+	currentTime := util.Now()
+
+	user, err := s.userRepository.Save(
+		ctx,
+		model.User{
+			ID:         id,
+			EmployeeID: externalUser.EmployeeID,
+			Name:       externalUser.Name,
+			HasAccess:  true,
+			CreatedBy:  id,
+			UpdatedBy:  id,
+			CreatedAt:  currentTime,
+			UpdatedAt:  currentTime,
+		},
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = s.roleRepository.AssignRole(
+		ctx,
+		user.ID,
+		constant.RoleNurse,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+	user.Roles = []string{constant.RoleNurse}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			ActorID:    user.ID,
+			Action:     constant.AuditActionCreate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   user.ID.String(),
+		},
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+// checkAccess turns away users whose access was revoked, or never granted.
+func checkAccess(user model.User) error {
+	if !user.HasAccess {
+		return fmt.Errorf(
+			"%w: user %s has no access",
+			constant.ErrUnauthorized,
+			user.ID,
+		)
+	}
+
+	return nil
+}
+
+func hashOIDCState(state string) string {
+	return hashRefreshToken(state)
+}
@@ -4,21 +4,31 @@ import (
 	"context"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/repository"
+	"github.com/nozzlium/halosuster/internal/util"
 )
 
 type PatientService struct {
+	transactor        *repository.Transactor
 	patientRepository *repository.PatientRepository
+	recordRepository  *repository.RecordRepository
+	auditService      *AuditService
 }
 
 func NewPatientService(
+	transactor *repository.Transactor,
 	patientRepository *repository.PatientRepository,
+	recordRepository *repository.RecordRepository,
+	auditService *AuditService,
 ) *PatientService {
 	return &PatientService{
+		transactor:        transactor,
 		patientRepository: patientRepository,
+		recordRepository:  recordRepository,
+		auditService:      auditService,
 	}
 }
 
@@ -26,18 +36,32 @@ func (s *PatientService) Create(
 	ctx context.Context,
 	patient model.Patient,
 ) (model.PatientResponseBody, error) {
-	userIdString := ctx.Value("userID").(string)
-	userId, err := uuid.Parse(
-		userIdString,
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.PatientResponseBody, error) {
+			return s.create(
+				ctx,
+				patient,
+			)
+		},
 	)
+}
+
+func (s *PatientService) create(
+	ctx context.Context,
+	patient model.Patient,
+) (model.PatientResponseBody, error) {
+	userId, err := auth.UserID(ctx)
 	if err != nil {
-		return model.PatientResponseBody{}, constant.ErrUnauthorized
+		return model.PatientResponseBody{}, err
 	}
 
 	currentTime := time.Now()
 	patient.CreatedAt = currentTime
 	patient.UpdatedAt = currentTime
 	patient.UserID = userId
+	patient.UpdatedBy = userId
 	saved, err := s.patientRepository.Create(
 		ctx,
 		patient,
@@ -46,37 +70,384 @@ func (s *PatientService) Create(
 		return model.PatientResponseBody{}, err
 	}
 
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionCreate,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   saved.IdentityIndex,
+		},
+	)
+	if err != nil {
+		return model.PatientResponseBody{}, err
+	}
+
 	return saved.ToResponseBody()
 }
 
 func (s *PatientService) FindAll(
 	ctx context.Context,
 	queries model.PatientQuery,
-) ([]model.PatientResponseBody, error) {
+) ([]model.PatientMaskedResponseBody, model.PageMeta, error) {
 	patients, err := s.patientRepository.FindAll(
 		ctx,
 		queries,
 	)
 	if err != nil {
-		return nil, err
+		return nil, model.PageMeta{}, err
 	}
 
+	meta, err := s.pageMeta(
+		ctx,
+		queries,
+		&patients,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	events := make(
+		[]model.AuditEvent,
+		0,
+		len(patients),
+	)
 	patientData := make(
-		[]model.PatientResponseBody,
+		[]model.PatientMaskedResponseBody,
 		0,
 		len(patients),
 	)
 	for _, patient := range patients {
-		data, err := patient.ToResponseBody()
-		if err != nil {
-			return nil, err
-		}
+		events = append(
+			events,
+			model.AuditEvent{
+				Action:     constant.AuditActionRead,
+				TargetType: constant.AuditTargetPatient,
+				TargetID:   patient.IdentityIndex,
+			},
+		)
 
 		patientData = append(
 			patientData,
+			patient.ToMaskedResponseBody(),
+		)
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		events...,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	return patientData, meta, nil
+}
+
+func (s *PatientService) pageMeta(
+	ctx context.Context,
+	queries model.PatientQuery,
+	patients *[]model.Patient,
+) (model.PageMeta, error) {
+	var (
+		meta    model.PageMeta
+		hasMore bool
+	)
+	*patients, hasMore = util.TrimPage(
+		*patients,
+		queries.Limit,
+	)
+	if len(*patients) > 0 {
+		last := (*patients)[len(*patients)-1]
+		meta.NextCursor = queries.List.NextCursor(
+			model.PatientKeyset,
+			hasMore,
+			last.CreatedAt,
+			last.IdentityIndex,
+		)
+	}
+
+	if queries.List.WithTotal {
+		total, err := s.patientRepository.Count(
+			ctx,
+			queries,
+		)
+		if err != nil {
+			return model.PageMeta{}, err
+		}
+		meta.Total = &total
+	}
+
+	return meta, nil
+}
+
+func (s *PatientService) Update(
+	ctx context.Context,
+	patient model.Patient,
+) (model.PatientResponseBody, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.PatientResponseBody, error) {
+			return s.update(
+				ctx,
+				patient,
+			)
+		},
+	)
+}
+
+func (s *PatientService) update(
+	ctx context.Context,
+	patient model.Patient,
+) (model.PatientResponseBody, error) {
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.PatientResponseBody{}, err
+	}
+
+	patient.UpdatedAt = time.Now()
+	patient.UpdatedBy = actorId
+	_, err = s.patientRepository.Edit(
+		ctx,
+		patient,
+	)
+	if err != nil {
+		return model.PatientResponseBody{}, err
+	}
+
+	saved, err := s.patientRepository.FindById(
+		ctx,
+		patient.IdentityNumber,
+	)
+	if err != nil {
+		return model.PatientResponseBody{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   saved.IdentityIndex,
+		},
+	)
+	if err != nil {
+		return model.PatientResponseBody{}, err
+	}
+
+	return saved.ToResponseBody()
+}
+
+func (s *PatientService) Delete(
+	ctx context.Context,
+	identityNumber string,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.delete(
+				ctx,
+				identityNumber,
+			)
+		},
+	)
+}
+
+func (s *PatientService) delete(
+	ctx context.Context,
+	identityNumber string,
+) error {
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.patientRepository.SetDeletedAt(
+		ctx,
+		model.Patient{
+			IdentityNumber: identityNumber,
+			UpdatedBy:      actorId,
+			DeletedAt:      time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionDelete,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   deleted.IdentityIndex,
+		},
+	)
+}
+
+func (s *PatientService) Restore(
+	ctx context.Context,
+	identityNumber string,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.restore(
+				ctx,
+				identityNumber,
+			)
+		},
+	)
+}
+
+func (s *PatientService) restore(
+	ctx context.Context,
+	identityNumber string,
+) error {
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	restored, err := s.patientRepository.Restore(
+		ctx,
+		model.Patient{
+			IdentityNumber: identityNumber,
+			UpdatedBy:      actorId,
+			UpdatedAt:      time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionRestore,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   restored.IdentityIndex,
+		},
+	)
+}
+
+// FindDetail audits the patient and every record shown as read.
+func (s *PatientService) FindDetail(
+	ctx context.Context,
+	queries model.RecordQuery,
+) (model.PatientDetailResponseBody, model.PageMeta, error) {
+	patient, err := s.patientRepository.FindById(
+		ctx,
+		queries.IdentityNumber,
+	)
+	if err != nil {
+		return model.PatientDetailResponseBody{}, model.PageMeta{}, err
+	}
+
+	queries.CreatedAt = string(model.Asc)
+	records, err := s.recordRepository.FindAll(
+		ctx,
+		queries,
+	)
+	if err != nil {
+		return model.PatientDetailResponseBody{}, model.PageMeta{}, err
+	}
+
+	meta, err := recordPageMeta(
+		ctx,
+		s.recordRepository,
+		queries,
+		&records,
+	)
+	if err != nil {
+		return model.PatientDetailResponseBody{}, model.PageMeta{}, err
+	}
+
+	events := make(
+		[]model.AuditEvent,
+		0,
+		len(records)+1,
+	)
+	events = append(
+		events,
+		model.AuditEvent{
+			Action:     constant.AuditActionRead,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   patient.IdentityIndex,
+		},
+	)
+	recordData := make(
+		[]model.RecordRevisionResponseBody,
+		0,
+		len(records),
+	)
+	for _, record := range records {
+		events = append(
+			events,
+			model.AuditEvent{
+				Action:     constant.AuditActionRead,
+				TargetType: constant.AuditTargetRecord,
+				TargetID:   record.RecordID.String(),
+			},
+		)
+
+		data, err := record.ToRevisionResponseBody()
+		if err != nil {
+			return model.PatientDetailResponseBody{}, model.PageMeta{}, err
+		}
+
+		recordData = append(
+			recordData,
 			data,
 		)
 	}
 
-	return patientData, nil
+	err = s.auditService.Record(
+		ctx,
+		events...,
+	)
+	if err != nil {
+		return model.PatientDetailResponseBody{}, model.PageMeta{}, err
+	}
+
+	return model.PatientDetailResponseBody{
+		PatientMaskedResponseBody: patient.ToMaskedResponseBody(),
+		IdentityCardScanImg:       patient.IdentityScanImg,
+		Records:                   recordData,
+	}, meta, nil
+}
+
+// Reveal is audited as a reveal rather than a read.
+func (s *PatientService) Reveal(
+	ctx context.Context,
+	identityNumber string,
+) (model.PatientRevealResponseBody, error) {
+	patient, err := s.patientRepository.FindById(
+		ctx,
+		identityNumber,
+	)
+	if err != nil {
+		return model.PatientRevealResponseBody{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionReveal,
+			TargetType: constant.AuditTargetPatient,
+			TargetID:   patient.IdentityIndex,
+		},
+	)
+	if err != nil {
+		return model.PatientRevealResponseBody{}, err
+	}
+
+	patientData, err := patient.ToResponseBody()
+	if err != nil {
+		return model.PatientRevealResponseBody{}, err
+	}
+
+	return model.PatientRevealResponseBody{
+		PatientResponseBody: patientData,
+		IdentityCardScanImg: patient.IdentityScanImg,
+	}, nil
 }
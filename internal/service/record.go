@@ -5,20 +5,28 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/repository"
+	"github.com/nozzlium/halosuster/internal/util"
 )
 
 type RecordService struct {
+	transactor       *repository.Transactor
 	recordRepository *repository.RecordRepository
+	auditService     *AuditService
 }
 
 func NewRecordService(
+	transactor *repository.Transactor,
 	recordRepository *repository.RecordRepository,
+	auditService *AuditService,
 ) *RecordService {
 	return &RecordService{
+		transactor:       transactor,
 		recordRepository: recordRepository,
+		auditService:     auditService,
 	}
 }
 
@@ -26,12 +34,25 @@ func (s *RecordService) Create(
 	ctx context.Context,
 	record model.Record,
 ) (model.Record, error) {
-	userIdString := ctx.Value("userID").(string)
-	userId, err := uuid.Parse(
-		userIdString,
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.Record, error) {
+			return s.create(
+				ctx,
+				record,
+			)
+		},
 	)
+}
+
+func (s *RecordService) create(
+	ctx context.Context,
+	record model.Record,
+) (model.Record, error) {
+	userId, err := auth.UserID(ctx)
 	if err != nil {
-		return model.Record{}, constant.ErrUnauthorized
+		return model.Record{}, err
 	}
 
 	currentTime := time.Now()
@@ -39,11 +60,14 @@ func (s *RecordService) Create(
 	record.UpdatedAt = currentTime
 
 	record.UserID = userId
+	record.CreatedBy = userId
 	id, err := uuid.NewV7()
 	if err != nil {
 		return model.Record{}, err
 	}
 	record.ID = id
+	record.RecordID = id
+	record.Revision = 1
 	saved, err := s.recordRepository.Create(
 		ctx,
 		record,
@@ -52,5 +76,228 @@ func (s *RecordService) Create(
 		return model.Record{}, err
 	}
 
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionCreate,
+			TargetType: constant.AuditTargetRecord,
+			TargetID:   saved.RecordID.String(),
+		},
+	)
+	if err != nil {
+		return model.Record{}, err
+	}
+
 	return saved, nil
 }
+
+func (s *RecordService) FindAll(
+	ctx context.Context,
+	queries model.RecordQuery,
+) ([]model.RecordResponseBody, model.PageMeta, error) {
+	records, err := s.recordRepository.FindAll(
+		ctx,
+		queries,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	meta, err := recordPageMeta(
+		ctx,
+		s.recordRepository,
+		queries,
+		&records,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	events := make(
+		[]model.AuditEvent,
+		0,
+		len(records),
+	)
+	recordData := make(
+		[]model.RecordResponseBody,
+		0,
+		len(records),
+	)
+	for _, record := range records {
+		events = append(
+			events,
+			model.AuditEvent{
+				Action:     constant.AuditActionRead,
+				TargetType: constant.AuditTargetRecord,
+				TargetID:   record.RecordID.String(),
+			},
+		)
+
+		data, err := record.ToResponseBody()
+		if err != nil {
+			return nil, model.PageMeta{}, err
+		}
+
+		recordData = append(
+			recordData,
+			data,
+		)
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		events...,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	return recordData, meta, nil
+}
+
+func recordPageMeta(
+	ctx context.Context,
+	recordRepository *repository.RecordRepository,
+	queries model.RecordQuery,
+	records *[]model.Record,
+) (model.PageMeta, error) {
+	var (
+		meta    model.PageMeta
+		hasMore bool
+	)
+	*records, hasMore = util.TrimPage(
+		*records,
+		queries.Limit,
+	)
+	if len(*records) > 0 {
+		last := (*records)[len(*records)-1]
+		meta.NextCursor = queries.List.NextCursor(
+			model.RecordKeyset,
+			hasMore,
+			last.CreatedAt,
+			last.ID.String(),
+		)
+	}
+
+	if queries.List.WithTotal {
+		total, err := recordRepository.Count(
+			ctx,
+			queries,
+		)
+		if err != nil {
+			return model.PageMeta{}, err
+		}
+		meta.Total = &total
+	}
+
+	return meta, nil
+}
+
+func (s *RecordService) Amend(
+	ctx context.Context,
+	record model.Record,
+) (model.RecordRevisionResponseBody, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.RecordRevisionResponseBody, error) {
+			return s.amend(
+				ctx,
+				record,
+			)
+		},
+	)
+}
+
+func (s *RecordService) amend(
+	ctx context.Context,
+	record model.Record,
+) (model.RecordRevisionResponseBody, error) {
+	userId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.RecordRevisionResponseBody{}, err
+	}
+
+	record.UpdatedAt = time.Now()
+	record.UserID = userId
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return model.RecordRevisionResponseBody{}, err
+	}
+	record.ID = id
+
+	saved, err := s.recordRepository.Amend(
+		ctx,
+		record,
+	)
+	if err != nil {
+		return model.RecordRevisionResponseBody{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetRecord,
+			TargetID:   saved.RecordID.String(),
+		},
+	)
+	if err != nil {
+		return model.RecordRevisionResponseBody{}, err
+	}
+
+	revisions, err := s.recordRepository.FindRevisions(
+		ctx,
+		saved.RecordID,
+	)
+	if err != nil {
+		return model.RecordRevisionResponseBody{}, err
+	}
+
+	return revisions[0].ToRevisionResponseBody()
+}
+
+func (s *RecordService) FindRevisions(
+	ctx context.Context,
+	recordID uuid.UUID,
+) ([]model.RecordRevisionResponseBody, error) {
+	revisions, err := s.recordRepository.FindRevisions(
+		ctx,
+		recordID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionRead,
+			TargetType: constant.AuditTargetRecord,
+			TargetID:   recordID.String(),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionData := make(
+		[]model.RecordRevisionResponseBody,
+		0,
+		len(revisions),
+	)
+	for _, revision := range revisions {
+		data, err := revision.ToRevisionResponseBody()
+		if err != nil {
+			return nil, err
+		}
+
+		revisionData = append(
+			revisionData,
+			data,
+		)
+	}
+
+	return revisionData, nil
+}
@@ -0,0 +1,560 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/jwtkey"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/repository"
+)
+
+type TokenService struct {
+	transactor      *repository.Transactor
+	tokenRepository *repository.TokenRepository
+	userRepository  *repository.UserRepository
+	keyring         *jwtkey.Keyring
+	issuer          string
+	audience        string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	challengeTTL    time.Duration
+}
+
+func NewTokenService(
+	transactor *repository.Transactor,
+	tokenRepository *repository.TokenRepository,
+	userRepository *repository.UserRepository,
+	keyring *jwtkey.Keyring,
+	issuer string,
+	audience string,
+	accessTokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	challengeTTL time.Duration,
+) *TokenService {
+	return &TokenService{
+		transactor:      transactor,
+		tokenRepository: tokenRepository,
+		userRepository:  userRepository,
+		keyring:         keyring,
+		issuer:          issuer,
+		audience:        audience,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		challengeTTL:    challengeTTL,
+	}
+}
+
+// Issue starts a session for user with a new refresh token family.
+func (s *TokenService) Issue(
+	ctx context.Context,
+	user model.User,
+) (model.TokenPair, error) {
+	familyID, err := uuid.NewV7()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	// the first token of a family shares its ID
+	return s.issue(
+		ctx,
+		user,
+		familyID,
+		familyID,
+	)
+}
+
+// Refresh rotates a refresh token. Replaying a rotated one revokes its family.
+func (s *TokenService) Refresh(
+	ctx context.Context,
+	refreshToken string,
+) (model.TokenPair, error) {
+	tokens, err := inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.TokenPair, error) {
+			return s.refresh(
+				ctx,
+				refreshToken,
+			)
+		},
+	)
+
+	if err != nil {
+		return model.TokenPair{}, revokeReplayed(
+			ctx,
+			err,
+			s.tokenRepository.RevokeRefreshTokenFamily,
+		)
+	}
+
+	return tokens, nil
+}
+
+// revokeReplayed revokes the family of a replayed token after the refresh rolled back.
+func revokeReplayed(
+	ctx context.Context,
+	err error,
+	revokeFamily func(ctx context.Context, familyID uuid.UUID, revokedAt time.Time) error,
+) error {
+	var replayed replayedTokenError
+	if !errors.As(
+		err,
+		&replayed,
+	) {
+		return err
+	}
+
+	err = revokeFamily(
+		ctx,
+		replayed.familyID,
+		time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return constant.ErrUnauthorized
+}
+
+// replayedTokenError makes Refresh revoke the family of the token.
+type replayedTokenError struct {
+	familyID uuid.UUID
+}
+
+func (e replayedTokenError) Error() string {
+	return "refresh token of family " + e.familyID.String() + " was replayed"
+}
+
+func (s *TokenService) refresh(
+	ctx context.Context,
+	refreshToken string,
+) (model.TokenPair, error) {
+	saved, err := s.tokenRepository.FindRefreshTokenByHash(
+		ctx,
+		hashRefreshToken(refreshToken),
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return model.TokenPair{}, constant.ErrUnauthorized
+		}
+		return model.TokenPair{}, err
+	}
+
+	currentTime := time.Now()
+	err = checkRefreshToken(
+		saved,
+		currentTime,
+	)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	user, err := s.userRepository.FindById(
+		ctx,
+		saved.UserID,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return model.TokenPair{}, constant.ErrUnauthorized
+		}
+		return model.TokenPair{}, err
+	}
+
+	replacementID, err := uuid.NewV7()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	saved.RevokedAt = currentTime
+	saved.ReplacedBy = replacementID
+	err = s.tokenRepository.RotateRefreshToken(
+		ctx,
+		saved,
+	)
+	if err != nil {
+		// someone else rotated it first
+		if errors.Is(
+			err,
+			constant.ErrConflict,
+		) {
+			return model.TokenPair{}, replayedTokenError{
+				familyID: saved.FamilyID,
+			}
+		}
+		return model.TokenPair{}, err
+	}
+
+	return s.issue(
+		ctx,
+		user,
+		saved.FamilyID,
+		replacementID,
+	)
+}
+
+// checkRefreshToken reports a token that was rotated before as replayed.
+func checkRefreshToken(
+	saved model.RefreshToken,
+	currentTime time.Time,
+) error {
+	if !saved.RevokedAt.IsZero() {
+		return replayedTokenError{
+			familyID: saved.FamilyID,
+		}
+	}
+
+	if currentTime.After(saved.ExpiresAt) {
+		return constant.ErrUnauthorized
+	}
+
+	return nil
+}
+
+// Revoke ends the session of an access token and its refresh token family.
+func (s *TokenService) Revoke(
+	ctx context.Context,
+	session model.Session,
+	refreshToken string,
+) error {
+	err := s.tokenRepository.RevokeAccessToken(
+		ctx,
+		session,
+	)
+	if err != nil {
+		return err
+	}
+
+	currentTime := time.Now()
+	if refreshToken != "" {
+		saved, err := s.tokenRepository.FindRefreshTokenByHash(
+			ctx,
+			hashRefreshToken(refreshToken),
+		)
+		if err != nil {
+			if !errors.Is(
+				err,
+				constant.ErrNotFound,
+			) {
+				return err
+			}
+		} else if saved.UserID == session.UserID {
+			err = s.tokenRepository.RevokeRefreshTokenFamily(
+				ctx,
+				saved.FamilyID,
+				currentTime,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.tokenRepository.DeleteExpiredAccessTokens(
+		ctx,
+		currentTime,
+	)
+}
+
+// RevokeAll ends every session of a user.
+func (s *TokenService) RevokeAll(
+	ctx context.Context,
+	userID uuid.UUID,
+) error {
+	err := s.tokenRepository.IncrementTokenVersion(
+		ctx,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.tokenRepository.RevokeUserRefreshTokens(
+		ctx,
+		userID,
+		time.Now(),
+	)
+}
+
+func (s *TokenService) Keyfunc(
+	token *jwt.Token,
+) (interface{}, error) {
+	return s.keyring.Keyfunc(token)
+}
+
+func (s *TokenService) JWKS() jwtkey.JWKS {
+	return s.keyring.JWKS()
+}
+
+// ValidateClaims checks an access token against the session state.
+func (s *TokenService) ValidateClaims(
+	ctx context.Context,
+	claims jwt.MapClaims,
+) error {
+	err := jwt.NewValidator(
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+	).Validate(claims)
+	if err != nil || claims["typ"] != accessTokenType {
+		return constant.ErrUnauthorized
+	}
+
+	session, err := SessionFromClaims(claims)
+	if err != nil {
+		return err
+	}
+
+	active, err := s.tokenRepository.IsSessionActive(
+		ctx,
+		session,
+	)
+	if err != nil {
+		return err
+	}
+	if !active {
+		return constant.ErrUnauthorized
+	}
+
+	return nil
+}
+
+func SessionFromClaims(
+	claims jwt.MapClaims,
+) (model.Session, error) {
+	var session model.Session
+
+	userIDClaim, ok := claims["si"].(string)
+	if !ok {
+		return session, constant.ErrUnauthorized
+	}
+	userIDBytes, err := base64.RawStdEncoding.DecodeString(
+		userIDClaim,
+	)
+	if err != nil {
+		return session, constant.ErrUnauthorized
+	}
+	session.UserID, err = uuid.ParseBytes(userIDBytes)
+	if err != nil {
+		return session, constant.ErrUnauthorized
+	}
+
+	tokenIDClaim, ok := claims["jti"].(string)
+	if !ok {
+		return session, constant.ErrUnauthorized
+	}
+	session.TokenID, err = uuid.Parse(tokenIDClaim)
+	if err != nil {
+		return session, constant.ErrUnauthorized
+	}
+
+	tokenVersion, ok := claims["tv"].(float64)
+	if !ok {
+		return session, constant.ErrUnauthorized
+	}
+	session.TokenVersion = int(tokenVersion)
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return session, constant.ErrUnauthorized
+	}
+	session.ExpiresAt = expiresAt.Time
+
+	return session, nil
+}
+
+func (s *TokenService) issue(
+	ctx context.Context,
+	user model.User,
+	familyID uuid.UUID,
+	refreshTokenID uuid.UUID,
+) (model.TokenPair, error) {
+	accessToken, err := s.generateAccessToken(user)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	refreshTokenBytes := make([]byte, 32)
+	_, err = rand.Read(refreshTokenBytes)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(
+		refreshTokenBytes,
+	)
+
+	currentTime := time.Now()
+	_, err = s.tokenRepository.SaveRefreshToken(
+		ctx,
+		model.RefreshToken{
+			ID:        refreshTokenID,
+			UserID:    user.ID,
+			FamilyID:  familyID,
+			TokenHash: hashRefreshToken(refreshToken),
+			ExpiresAt: currentTime.Add(s.refreshTokenTTL),
+			CreatedAt: currentTime,
+		},
+	)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	return model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *TokenService) generateAccessToken(
+	user model.User,
+) (string, error) {
+	tokenID, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+
+	currentTime := time.Now()
+	claims := jwt.MapClaims{}
+	userID := base64.RawStdEncoding.EncodeToString(
+		[]byte(user.ID.String()),
+	)
+	employeeId := user.EmployeeID
+	claims["si"] = userID
+	claims["ut"] = employeeId
+	claims["rl"] = user.Roles
+	// without roles the token only allows changing the password and logging out
+	if user.MustChangePassword {
+		claims["rl"] = []string{}
+		claims["pc"] = true
+	}
+	claims["tv"] = user.TokenVersion
+	claims["typ"] = accessTokenType
+	claims["iss"] = s.issuer
+	claims["aud"] = s.audience
+	claims["jti"] = tokenID.String()
+	claims["iat"] = currentTime.Unix()
+	claims["exp"] = currentTime.
+		Add(s.accessTokenTTL).
+		Unix()
+
+	t, err := s.keyring.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return t, nil
+}
+
+// IssueChallenge returns a token that is exchanged for a token pair with a valid code.
+func (s *TokenService) IssueChallenge(
+	user model.User,
+) (string, error) {
+	tokenID, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+
+	currentTime := time.Now()
+	return s.keyring.Sign(
+		jwt.MapClaims{
+			"typ": challengeTokenType,
+			"iss": s.issuer,
+			"aud": s.challengeAudience(),
+			"cs":  user.ID.String(),
+			"jti": tokenID.String(),
+			"iat": currentTime.Unix(),
+			"exp": currentTime.
+				Add(s.challengeTTL).
+				Unix(),
+		},
+	)
+}
+
+// UseChallenge returns the user of a challenge token, which works once.
+func (s *TokenService) UseChallenge(
+	ctx context.Context,
+	challengeToken string,
+) (uuid.UUID, error) {
+	token, err := jwt.Parse(
+		challengeToken,
+		s.keyring.Keyfunc,
+		jwt.WithValidMethods(
+			s.keyring.Methods(),
+		),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.challengeAudience()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != challengeTokenType {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+	userIDClaim, ok := claims["cs"].(string)
+	if !ok {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+	userID, err := uuid.Parse(userIDClaim)
+	if err != nil {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+	tokenIDClaim, ok := claims["jti"].(string)
+	if !ok {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+	tokenID, err := uuid.Parse(tokenIDClaim)
+	if err != nil {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return uuid.Nil, constant.ErrUnauthorized
+	}
+
+	err = s.tokenRepository.UseChallenge(
+		ctx,
+		tokenID,
+		expiresAt.Time,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrConflict,
+		) {
+			return uuid.Nil, constant.ErrUnauthorized
+		}
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// challengeAudience keeps challenge tokens from passing for access tokens.
+func (s *TokenService) challengeAudience() string {
+	return s.audience + "/2fa"
+}
+
+const (
+	accessTokenType    = "access"
+	challengeTokenType = "2fa"
+)
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/db"
+	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/jwtkey"
+	"github.com/nozzlium/halosuster/internal/migration"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/repository"
+)
+
+// testPool connects to TEST_DATABASE_URL and migrates it, skipping the
+// test when it is not set.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(
+		ctx,
+		url,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	migrator, err := migration.New(
+		pool,
+		db.PrimaryMigrations,
+		db.PrimaryMigrationsDir,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+func newTestTokenService(
+	t *testing.T,
+	pool *pgxpool.Pool,
+) (*TokenService, model.User) {
+	t.Helper()
+
+	keyring, err := jwtkey.NewKeyring(
+		config.JWTConfig{
+			JWTSecret: "token-test-secret",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentTime := time.Now()
+	userRepository := repository.NewUserRepository(pool)
+	user, err := userRepository.Save(
+		context.Background(),
+		model.User{
+			ID: id,
+			EmployeeID: fmt.Sprintf(
+				"3031202401%05d",
+				rand.IntN(100000),
+			),
+			Name:      "Token Test",
+			Password:  "not-a-hash",
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewTokenService(
+		repository.NewTransactor(pool),
+		repository.NewTokenRepository(pool),
+		userRepository,
+		keyring,
+		"halosuster",
+		"halosuster-api",
+		time.Minute,
+		time.Hour,
+		time.Minute,
+	), user
+}
+
+func TestTokenServiceRefreshReplay(t *testing.T) {
+	pool := testPool(t)
+	tokenService, user := newTestTokenService(
+		t,
+		pool,
+	)
+	ctx := context.Background()
+
+	first, err := tokenService.Issue(
+		ctx,
+		user,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tokenService.Refresh(
+		ctx,
+		first.RefreshToken,
+	)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+
+	_, err = tokenService.Refresh(
+		ctx,
+		first.RefreshToken,
+	)
+	if !errors.Is(err, constant.ErrUnauthorized) {
+		t.Fatalf("replayed refresh: got %v, want %v", err, constant.ErrUnauthorized)
+	}
+
+	// the replay revoked the whole family, including the successor
+	_, err = tokenService.Refresh(
+		ctx,
+		second.RefreshToken,
+	)
+	if !errors.Is(err, constant.ErrUnauthorized) {
+		t.Fatalf("successor refresh: got %v, want %v", err, constant.ErrUnauthorized)
+	}
+}
+
+func TestTokenServiceRefreshRotates(t *testing.T) {
+	pool := testPool(t)
+	tokenService, user := newTestTokenService(
+		t,
+		pool,
+	)
+	ctx := context.Background()
+
+	tokens, err := tokenService.Issue(
+		ctx,
+		user,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		tokens, err = tokenService.Refresh(
+			ctx,
+			tokens.RefreshToken,
+		)
+		if err != nil {
+			t.Fatalf("refresh %d: %v", i, err)
+		}
+	}
+}
+
+func TestCheckRefreshToken(t *testing.T) {
+	now := time.Now()
+	familyID := uuid.New()
+
+	tests := []struct {
+		name  string
+		saved model.RefreshToken
+		want  error
+	}{
+		{
+			name: "unused",
+			saved: model.RefreshToken{
+				FamilyID:  familyID,
+				ExpiresAt: now.Add(time.Hour),
+			},
+		},
+		{
+			name: "expired",
+			saved: model.RefreshToken{
+				FamilyID:  familyID,
+				ExpiresAt: now.Add(-time.Second),
+			},
+			want: constant.ErrUnauthorized,
+		},
+		{
+			name: "already rotated",
+			saved: model.RefreshToken{
+				FamilyID:  familyID,
+				ExpiresAt: now.Add(time.Hour),
+				RevokedAt: now.Add(-time.Minute),
+			},
+			want: replayedTokenError{familyID: familyID},
+		},
+		{
+			// a replay is a replay, however long ago the token expired
+			name: "rotated and expired",
+			saved: model.RefreshToken{
+				FamilyID:  familyID,
+				ExpiresAt: now.Add(-time.Second),
+				RevokedAt: now.Add(-time.Minute),
+			},
+			want: replayedTokenError{familyID: familyID},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkRefreshToken(
+				test.saved,
+				now,
+			)
+			if !errors.Is(err, test.want) {
+				t.Errorf("checkRefreshToken = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestRevokeReplayed(t *testing.T) {
+	familyID := uuid.New()
+	revokeErr := errors.New("connection reset")
+
+	tests := []struct {
+		name        string
+		err         error
+		revokeErr   error
+		wantRevoked bool
+		want        error
+	}{
+		{
+			name:        "replayed token",
+			err:         replayedTokenError{familyID: familyID},
+			wantRevoked: true,
+			want:        constant.ErrUnauthorized,
+		},
+		{
+			name: "replay wrapped by the transaction",
+			err: fmt.Errorf(
+				"refresh: %w",
+				replayedTokenError{familyID: familyID},
+			),
+			wantRevoked: true,
+			want:        constant.ErrUnauthorized,
+		},
+		{
+			name:        "revocation fails",
+			err:         replayedTokenError{familyID: familyID},
+			revokeErr:   revokeErr,
+			wantRevoked: true,
+			want:        revokeErr,
+		},
+		{
+			name: "unknown token",
+			err:  constant.ErrUnauthorized,
+			want: constant.ErrUnauthorized,
+		},
+		{
+			name: "other error",
+			err:  revokeErr,
+			want: revokeErr,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var revoked []uuid.UUID
+			err := revokeReplayed(
+				context.Background(),
+				test.err,
+				func(
+					ctx context.Context,
+					familyID uuid.UUID,
+					revokedAt time.Time,
+				) error {
+					revoked = append(revoked, familyID)
+					return test.revokeErr
+				},
+			)
+			if !errors.Is(err, test.want) {
+				t.Errorf("revokeReplayed = %v, want %v", err, test.want)
+			}
+
+			if !test.wantRevoked {
+				if len(revoked) != 0 {
+					t.Errorf("revoked %v, want nothing", revoked)
+				}
+				return
+			}
+			if len(revoked) != 1 || revoked[0] != familyID {
+				t.Errorf("revoked %v, want [%v]", revoked, familyID)
+			}
+		})
+	}
+}
+
+func TestTokenServiceChallengeSingleUse(t *testing.T) {
+	pool := testPool(t)
+	tokenService, user := newTestTokenService(
+		t,
+		pool,
+	)
+	ctx := context.Background()
+
+	challenge, err := tokenService.IssueChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := tokenService.UseChallenge(
+		ctx,
+		challenge,
+	)
+	if err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if userID != user.ID {
+		t.Errorf("UseChallenge = %v, want %v", userID, user.ID)
+	}
+
+	_, err = tokenService.UseChallenge(
+		ctx,
+		challenge,
+	)
+	if !errors.Is(err, constant.ErrUnauthorized) {
+		t.Fatalf("second use: got %v, want %v", err, constant.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nozzlium/halosuster/internal/repository"
+)
+
+func inTransaction[T any](
+	ctx context.Context,
+	transactor *repository.Transactor,
+	fn func(ctx context.Context) (T, error),
+) (T, error) {
+	var result T
+	err := transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			var err error
+			result, err = fn(ctx)
+			return err
+		},
+	)
+
+	return result, err
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
+	"github.com/nozzlium/halosuster/internal/constant"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/repository"
+	"github.com/nozzlium/halosuster/internal/totp"
+)
+
+const recoveryCodeCount = 10
+
+type TwoFactorService struct {
+	transactor          *repository.Transactor
+	twoFactorRepository *repository.TwoFactorRepository
+	userRepository      *repository.UserRepository
+	auditService        *AuditService
+	issuer              string
+}
+
+func NewTwoFactorService(
+	transactor *repository.Transactor,
+	twoFactorRepository *repository.TwoFactorRepository,
+	userRepository *repository.UserRepository,
+	auditService *AuditService,
+	issuer string,
+) *TwoFactorService {
+	return &TwoFactorService{
+		transactor:          transactor,
+		twoFactorRepository: twoFactorRepository,
+		userRepository:      userRepository,
+		auditService:        auditService,
+		issuer:              issuer,
+	}
+}
+
+// Enroll replaces a pending secret until a code from it is verified.
+func (s *TwoFactorService) Enroll(
+	ctx context.Context,
+) (model.TwoFactorEnrollResponseBody, error) {
+	userId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.TwoFactorEnrollResponseBody{}, err
+	}
+
+	user, err := s.userRepository.FindById(
+		ctx,
+		userId,
+	)
+	if err != nil {
+		return model.TwoFactorEnrollResponseBody{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return model.TwoFactorEnrollResponseBody{}, err
+	}
+
+	_, err = s.twoFactorRepository.SaveEnrollment(
+		ctx,
+		model.TOTP{
+			UserID:    userId,
+			Secret:    secret,
+			CreatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return model.TwoFactorEnrollResponseBody{}, err
+	}
+
+	return model.TwoFactorEnrollResponseBody{
+		Secret: secret,
+		OTPAuthURL: totp.URL(
+			s.issuer,
+			user.EmployeeID,
+			secret,
+		),
+	}, nil
+}
+
+func (s *TwoFactorService) Verify(
+	ctx context.Context,
+	code string,
+) (model.TwoFactorVerifyResponseBody, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.TwoFactorVerifyResponseBody, error) {
+			return s.verify(
+				ctx,
+				code,
+			)
+		},
+	)
+}
+
+func (s *TwoFactorService) verify(
+	ctx context.Context,
+	code string,
+) (model.TwoFactorVerifyResponseBody, error) {
+	userId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.TwoFactorVerifyResponseBody{}, err
+	}
+
+	saved, err := s.twoFactorRepository.FindTOTP(
+		ctx,
+		userId,
+	)
+	if err != nil {
+		return model.TwoFactorVerifyResponseBody{}, err
+	}
+	if saved.Enabled() {
+		return model.TwoFactorVerifyResponseBody{}, constant.ErrConflict
+	}
+
+	currentTime := time.Now()
+	step, ok := totp.Validate(
+		saved.Secret,
+		code,
+		currentTime,
+		saved.LastStep,
+	)
+	if !ok {
+		return model.TwoFactorVerifyResponseBody{}, constant.ErrBadInput
+	}
+
+	saved.EnabledAt = currentTime
+	saved.LastStep = step
+	_, err = s.twoFactorRepository.Enable(
+		ctx,
+		saved,
+	)
+	if err != nil {
+		return model.TwoFactorVerifyResponseBody{}, err
+	}
+
+	recoveryCodes, err := s.replaceRecoveryCodes(
+		ctx,
+		userId,
+	)
+	if err != nil {
+		return model.TwoFactorVerifyResponseBody{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   userId.String(),
+		},
+	)
+	if err != nil {
+		return model.TwoFactorVerifyResponseBody{}, err
+	}
+
+	return model.TwoFactorVerifyResponseBody{
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *TwoFactorService) IsEnabled(
+	ctx context.Context,
+	userID uuid.UUID,
+) (bool, error) {
+	saved, err := s.twoFactorRepository.FindTOTP(
+		ctx,
+		userID,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return saved.Enabled(), nil
+}
+
+// Check accepts each authenticator or recovery code once.
+func (s *TwoFactorService) Check(
+	ctx context.Context,
+	userID uuid.UUID,
+	code string,
+	recoveryCode string,
+) error {
+	saved, err := s.twoFactorRepository.FindTOTP(
+		ctx,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if !saved.Enabled() {
+		return constant.ErrUnauthorized
+	}
+
+	if recoveryCode != "" {
+		err = s.twoFactorRepository.UseRecoveryCode(
+			ctx,
+			userID,
+			hashRecoveryCode(recoveryCode),
+			time.Now(),
+		)
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return constant.ErrBadInput
+		}
+		return err
+	}
+
+	step, ok := totp.Validate(
+		saved.Secret,
+		code,
+		time.Now(),
+		saved.LastStep,
+	)
+	if !ok {
+		return constant.ErrBadInput
+	}
+
+	return s.twoFactorRepository.UseStep(
+		ctx,
+		userID,
+		step,
+	)
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(
+	ctx context.Context,
+	userID uuid.UUID,
+) ([]string, error) {
+	currentTime := time.Now()
+	plain := make(
+		[]string,
+		0,
+		recoveryCodeCount,
+	)
+	codes := make(
+		[]model.RecoveryCode,
+		0,
+		recoveryCodeCount,
+	)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+
+		plain = append(plain, code)
+		codes = append(
+			codes,
+			model.RecoveryCode{
+				ID:        id,
+				UserID:    userID,
+				CodeHash:  hashRecoveryCode(code),
+				CreatedAt: currentTime,
+			},
+		)
+	}
+
+	err := s.twoFactorRepository.ReplaceRecoveryCodes(
+		ctx,
+		userID,
+		codes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return plain, nil
+}
+
+// generateRecoveryCode returns 50 random bits, e.g. ABCDE-FGHIJ.
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, 7)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+
+	code := base32.StdEncoding.EncodeToString(random)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode ignores case and dashes.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(
+		strings.ReplaceAll(code, "-", ""),
+	)
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,12 +2,12 @@ package service
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/auth"
+	"github.com/nozzlium/halosuster/internal/config"
 	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/model"
 	"github.com/nozzlium/halosuster/internal/repository"
@@ -16,27 +16,70 @@ import (
 )
 
 type UserService struct {
-	userRepository *repository.UserRepository
-	salt           int
-	secret         string
+	transactor       *repository.Transactor
+	userRepository   *repository.UserRepository
+	roleRepository   *repository.RoleRepository
+	tokenService     *TokenService
+	auditService     *AuditService
+	throttleService  *LoginThrottleService
+	twoFactorService *TwoFactorService
+	passwordPolicy   config.PasswordPolicyConfig
+	salt             int
 }
 
 func NewUserService(
+	transactor *repository.Transactor,
 	userRepository *repository.UserRepository,
+	roleRepository *repository.RoleRepository,
+	tokenService *TokenService,
+	auditService *AuditService,
+	throttleService *LoginThrottleService,
+	twoFactorService *TwoFactorService,
+	passwordPolicy config.PasswordPolicyConfig,
 	salt int,
-	secret string,
 ) *UserService {
 	return &UserService{
-		userRepository: userRepository,
-		salt:           salt,
-		secret:         secret,
+		transactor:       transactor,
+		userRepository:   userRepository,
+		roleRepository:   roleRepository,
+		tokenService:     tokenService,
+		auditService:     auditService,
+		throttleService:  throttleService,
+		twoFactorService: twoFactorService,
+		passwordPolicy:   passwordPolicy,
+		salt:             salt,
 	}
 }
 
+// Register creates an IT user on behalf of the IT user in ctx.
 func (s *UserService) Register(
 	ctx context.Context,
 	user model.User,
-) (model.UserRegisterResponseBody, error) {
+) (model.UserDataResponseBody, error) {
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.UserDataResponseBody{}, err
+	}
+
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.UserDataResponseBody, error) {
+			return s.register(
+				ctx,
+				user,
+				actorId,
+			)
+		},
+	)
+}
+
+// register saves an IT user, their own creator when createdBy is uuid.Nil.
+func (s *UserService) register(
+	ctx context.Context,
+	user model.User,
+	createdBy uuid.UUID,
+) (model.UserDataResponseBody, error) {
 	savedUser, err := s.userRepository.FindByEmployeeId(
 		ctx,
 		user.EmployeeID,
@@ -46,17 +89,17 @@ func (s *UserService) Register(
 			err,
 			constant.ErrNotFound,
 		) {
-			return model.UserRegisterResponseBody{}, err
+			return model.UserDataResponseBody{}, err
 		}
 	}
 
 	if savedUser.EmployeeID == user.EmployeeID {
-		return model.UserRegisterResponseBody{}, constant.ErrConflict
+		return model.UserDataResponseBody{}, constant.ErrConflict
 	}
 
 	id, err := uuid.NewV7()
 	if err != nil {
-		return model.UserRegisterResponseBody{}, err
+		return model.UserDataResponseBody{}, err
 	}
 	currentTime := util.Now()
 	hashedPassword, err := bcrypt.GenerateFromPassword(
@@ -64,13 +107,19 @@ func (s *UserService) Register(
 		s.salt,
 	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, err
+		return model.UserDataResponseBody{}, err
 	}
 
+	if createdBy == uuid.Nil {
+		createdBy = id
+	}
 	user.ID = id
 	user.Password = string(
 		hashedPassword,
 	)
+	user.HasAccess = true
+	user.CreatedBy = createdBy
+	user.UpdatedBy = createdBy
 	user.CreatedAt = currentTime
 	user.UpdatedAt = currentTime
 
@@ -79,84 +128,287 @@ func (s *UserService) Register(
 		user,
 	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, err
+		return model.UserDataResponseBody{}, err
 	}
 
-	accessToken, err := generateJwtToken(
-		s.secret,
-		result,
+	err = s.roleRepository.AssignRole(
+		ctx,
+		result.ID,
+		constant.RoleIT,
 	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, err
+		return model.UserDataResponseBody{}, err
 	}
+	result.Roles = []string{constant.RoleIT}
 
-	userResponseBody, err := result.ToUserRegisterResponseBody()
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			ActorID:    createdBy,
+			Action:     constant.AuditActionCreate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   result.ID.String(),
+		},
+	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, err
+		return model.UserDataResponseBody{}, err
 	}
-	userResponseBody.AccessToken = accessToken
 
-	return userResponseBody, nil
+	return result.ToUserDataResponseBody()
+}
+
+// BootstrapAdmin registers the first IT user and refuses once one exists.
+func (s *UserService) BootstrapAdmin(
+	ctx context.Context,
+	user model.User,
+) (model.UserDataResponseBody, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.UserDataResponseBody, error) {
+			existing, err := s.userRepository.FindAll(
+				ctx,
+				model.SearchUserQuery{
+					Role:  constant.RoleIT,
+					Limit: 1,
+				},
+			)
+			if err != nil {
+				return model.UserDataResponseBody{}, err
+			}
+			if len(existing) > 0 {
+				return model.UserDataResponseBody{}, constant.ErrConflict
+			}
+
+			return s.register(
+				ctx,
+				user,
+				uuid.Nil,
+			)
+		},
+	)
 }
 
 func (s *UserService) Login(
 	ctx context.Context,
 	user model.User,
 ) (model.UserRegisterResponseBody, error) {
-	savedUser, err := s.userRepository.FindByEmployeeId(
+	savedUser, err := s.authenticate(
 		ctx,
-		user.EmployeeID,
+		user,
+		constant.RoleIT,
 	)
 	if err != nil {
 		return model.UserRegisterResponseBody{}, err
 	}
 
-	err = bcrypt.CompareHashAndPassword(
-		[]byte(savedUser.Password),
-		[]byte(user.Password),
+	return s.completeLogin(
+		ctx,
+		savedUser,
+	)
+}
+
+// completeLogin issues a token pair, or a challenge token under two-factor.
+func (s *UserService) completeLogin(
+	ctx context.Context,
+	user model.User,
+) (model.UserRegisterResponseBody, error) {
+	twoFactor, err := s.twoFactorService.IsEnabled(
+		ctx,
+		user.ID,
 	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, constant.ErrBadInput
+		return model.UserRegisterResponseBody{}, err
 	}
+	if twoFactor {
+		challengeToken, err := s.tokenService.IssueChallenge(
+			user,
+		)
+		if err != nil {
+			return model.UserRegisterResponseBody{}, err
+		}
 
-	accessToken, err := generateJwtToken(
-		s.secret,
-		savedUser,
+		userResponseBody, err := user.ToUserRegisterResponseBody()
+		if err != nil {
+			return model.UserRegisterResponseBody{}, err
+		}
+		userResponseBody.ChallengeToken = challengeToken
+
+		return userResponseBody, nil
+	}
+
+	return s.startSession(
+		ctx,
+		user,
+	)
+}
+
+// LoginTwoFactor exchanges a challenge token and a code for a token pair.
+func (s *UserService) LoginTwoFactor(
+	ctx context.Context,
+	body model.TwoFactorLoginBody,
+) (model.UserRegisterResponseBody, error) {
+	// the NIP is only known from the challenge
+	err := s.throttleService.CheckIP(ctx)
+	if err != nil {
+		return model.UserRegisterResponseBody{}, err
+	}
+
+	userID, err := s.tokenService.UseChallenge(
+		ctx,
+		body.ChallengeToken,
 	)
 	if err != nil {
 		return model.UserRegisterResponseBody{}, err
 	}
 
-	userResponseBody, err := savedUser.ToUserRegisterResponseBody()
+	// the user may have lost access since the password was accepted
+	savedUser, err := s.userRepository.FindById(
+		ctx,
+		userID,
+	)
+	if err == nil &&
+		(!savedUser.HasRole(constant.RoleIT) || !savedUser.HasAccess) {
+		err = constant.ErrNotFound
+	}
 	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrNotFound,
+		) {
+			return model.UserRegisterResponseBody{}, constant.ErrUnauthorized
+		}
 		return model.UserRegisterResponseBody{}, err
 	}
-	userResponseBody.AccessToken = accessToken
 
-	return userResponseBody, nil
+	err = s.throttleService.Check(
+		ctx,
+		savedUser.EmployeeID,
+	)
+	if err != nil {
+		return model.UserRegisterResponseBody{}, err
+	}
+
+	err = s.twoFactorService.Check(
+		ctx,
+		savedUser.ID,
+		body.Code,
+		body.RecoveryCode,
+	)
+	if err != nil {
+		if errors.Is(
+			err,
+			constant.ErrBadInput,
+		) {
+			failErr := s.throttleService.Fail(
+				ctx,
+				savedUser.EmployeeID,
+			)
+			if failErr != nil {
+				return model.UserRegisterResponseBody{}, failErr
+			}
+		}
+		return model.UserRegisterResponseBody{}, err
+	}
+
+	return s.startSession(
+		ctx,
+		savedUser,
+	)
+}
+
+// authenticate checks the credentials of user, counting failures towards the lockout.
+func (s *UserService) authenticate(
+	ctx context.Context,
+	user model.User,
+	role string,
+) (model.User, error) {
+	err := s.throttleService.Check(
+		ctx,
+		user.EmployeeID,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	savedUser, err := s.userRepository.FindByEmployeeId(
+		ctx,
+		user.EmployeeID,
+	)
+	if err == nil &&
+		(!savedUser.HasRole(role) || !savedUser.HasAccess) {
+		err = constant.ErrNotFound
+	}
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword(
+			[]byte(savedUser.Password),
+			[]byte(user.Password),
+		)
+		if err != nil {
+			err = constant.ErrBadInput
+		}
+	}
+	if err != nil {
+		if errors.Is(err, constant.ErrNotFound) ||
+			errors.Is(err, constant.ErrBadInput) {
+			failErr := s.throttleService.Fail(
+				ctx,
+				user.EmployeeID,
+			)
+			if failErr != nil {
+				return model.User{}, failErr
+			}
+		}
+		return model.User{}, err
+	}
+
+	return savedUser, nil
 }
 
 func (s *UserService) FindAll(
 	ctx context.Context,
 	queries model.SearchUserQuery,
-) ([]model.UserDataResponseBody, error) {
+) ([]model.UserDataResponseBody, model.PageMeta, error) {
 	users, err := s.userRepository.FindAll(
 		ctx,
 		queries,
 	)
 	if err != nil {
-		return nil, err
+		return nil, model.PageMeta{}, err
+	}
+
+	meta, err := s.pageMeta(
+		ctx,
+		queries,
+		&users,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
 	}
 
+	events := make(
+		[]model.AuditEvent,
+		0,
+		len(users),
+	)
 	usersDataCol := make(
 		[]model.UserDataResponseBody,
 		0,
 		len(users),
 	)
 	for _, user := range users {
+		events = append(
+			events,
+			model.AuditEvent{
+				Action:     constant.AuditActionRead,
+				TargetType: constant.AuditTargetUser,
+				TargetID:   user.ID.String(),
+			},
+		)
+
 		userData, err := user.ToUserDataResponseBody()
 		if err != nil {
-			return nil, err
+			return nil, model.PageMeta{}, err
 		}
 
 		usersDataCol = append(
@@ -165,50 +417,74 @@ func (s *UserService) FindAll(
 		)
 	}
 
-	return usersDataCol, nil
+	err = s.auditService.Record(
+		ctx,
+		events...,
+	)
+	if err != nil {
+		return nil, model.PageMeta{}, err
+	}
+
+	return usersDataCol, meta, nil
 }
 
-func generateJwtToken(
-	secret string,
-	user model.User,
-) (string, error) {
-	token := jwt.New(
-		jwt.SigningMethodHS256,
+func (s *UserService) pageMeta(
+	ctx context.Context,
+	queries model.SearchUserQuery,
+	users *[]model.User,
+) (model.PageMeta, error) {
+	var (
+		meta    model.PageMeta
+		hasMore bool
 	)
-
-	claims := token.Claims.(jwt.MapClaims)
-	userID := base64.RawStdEncoding.EncodeToString(
-		[]byte(user.ID.String()),
+	*users, hasMore = util.TrimPage(
+		*users,
+		queries.Limit,
 	)
-	employeeId := user.EmployeeID
-	claims["si"] = userID
-	claims["ut"] = employeeId
-	claims["exp"] = time.Now().
-		Add(time.Hour * 72).
-		Unix()
+	if len(*users) > 0 {
+		last := (*users)[len(*users)-1]
+		meta.NextCursor = queries.List.NextCursor(
+			model.UserKeyset,
+			hasMore,
+			last.CreatedAt,
+			last.ID.String(),
+		)
+	}
 
-	t, err := token.SignedString(
-		[]byte(secret),
-	)
-	if err != nil {
-		return "", err
+	if queries.List.WithTotal {
+		total, err := s.userRepository.Count(
+			ctx,
+			queries,
+		)
+		if err != nil {
+			return model.PageMeta{}, err
+		}
+		meta.Total = &total
 	}
 
-	return t, nil
+	return meta, nil
 }
 
 func (s *UserService) RegisterNurse(
 	ctx context.Context,
 	user model.User,
 ) (model.NurseRegisterResponseBody, error) {
-	employeeId := ctx.Value("employeeId").(string)
-	err := util.ValidateUserEmployeeID(
-		employeeId,
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.NurseRegisterResponseBody, error) {
+			return s.registerNurse(
+				ctx,
+				user,
+			)
+		},
 	)
-	if err != nil {
-		return model.NurseRegisterResponseBody{}, constant.ErrUnauthorized
-	}
+}
 
+func (s *UserService) registerNurse(
+	ctx context.Context,
+	user model.User,
+) (model.NurseRegisterResponseBody, error) {
 	savedNurse, err := s.userRepository.FindByEmployeeId(
 		ctx,
 		user.EmployeeID,
@@ -226,6 +502,11 @@ func (s *UserService) RegisterNurse(
 		return model.NurseRegisterResponseBody{}, constant.ErrConflict
 	}
 
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.NurseRegisterResponseBody{}, err
+	}
+
 	id, err := uuid.NewV7()
 	if err != nil {
 		return model.NurseRegisterResponseBody{}, err
@@ -233,6 +514,8 @@ func (s *UserService) RegisterNurse(
 	currentTime := util.Now()
 
 	user.ID = id
+	user.CreatedBy = actorId
+	user.UpdatedBy = user.CreatedBy
 	user.CreatedAt = currentTime
 	user.UpdatedAt = currentTime
 
@@ -244,6 +527,27 @@ func (s *UserService) RegisterNurse(
 		return model.NurseRegisterResponseBody{}, err
 	}
 
+	err = s.roleRepository.AssignRole(
+		ctx,
+		result.ID,
+		constant.RoleNurse,
+	)
+	if err != nil {
+		return model.NurseRegisterResponseBody{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionCreate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   result.ID.String(),
+		},
+	)
+	if err != nil {
+		return model.NurseRegisterResponseBody{}, err
+	}
+
 	return result.ToNurseResponseBody()
 }
 
@@ -251,35 +555,47 @@ func (s *UserService) LoginNurse(
 	ctx context.Context,
 	user model.User,
 ) (model.UserRegisterResponseBody, error) {
-	savedUser, err := s.userRepository.FindByEmployeeId(
+	savedUser, err := s.authenticate(
 		ctx,
-		user.EmployeeID,
+		user,
+		constant.RoleNurse,
 	)
 	if err != nil {
 		return model.UserRegisterResponseBody{}, err
 	}
 
-	err = bcrypt.CompareHashAndPassword(
-		[]byte(savedUser.Password),
-		[]byte(user.Password),
+	return s.startSession(
+		ctx,
+		savedUser,
+	)
+}
+
+func (s *UserService) startSession(
+	ctx context.Context,
+	user model.User,
+) (model.UserRegisterResponseBody, error) {
+	err := s.throttleService.Reset(
+		ctx,
+		user.EmployeeID,
 	)
 	if err != nil {
-		return model.UserRegisterResponseBody{}, constant.ErrBadInput
+		return model.UserRegisterResponseBody{}, err
 	}
 
-	accessToken, err := generateJwtToken(
-		s.secret,
-		savedUser,
+	tokens, err := s.tokenService.Issue(
+		ctx,
+		user,
 	)
 	if err != nil {
 		return model.UserRegisterResponseBody{}, err
 	}
 
-	userResponseBody, err := savedUser.ToUserRegisterResponseBody()
+	userResponseBody, err := user.ToUserRegisterResponseBody()
 	if err != nil {
 		return model.UserRegisterResponseBody{}, err
 	}
-	userResponseBody.AccessToken = accessToken
+	userResponseBody.AccessToken = tokens.AccessToken
+	userResponseBody.RefreshToken = tokens.RefreshToken
 
 	return userResponseBody, nil
 }
@@ -288,14 +604,21 @@ func (s *UserService) GrantNurseAccess(
 	ctx context.Context,
 	user model.User,
 ) error {
-	employeeId := ctx.Value("employeeId").(string)
-	err := util.ValidateUserEmployeeID(
-		employeeId,
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.grantNurseAccess(
+				ctx,
+				user,
+			)
+		},
 	)
-	if err != nil {
-		return constant.ErrUnauthorized
-	}
+}
 
+func (s *UserService) grantNurseAccess(
+	ctx context.Context,
+	user model.User,
+) error {
 	savedNurse, err := s.userRepository.FindById(
 		ctx,
 		user.ID,
@@ -304,11 +627,13 @@ func (s *UserService) GrantNurseAccess(
 		return err
 	}
 
-	err = util.ValidateGeneralEmployeeID(
-		savedNurse.EmployeeID,
-	)
+	if !savedNurse.HasRole(constant.RoleNurse) {
+		return constant.ErrNotFound
+	}
+
+	actorId, err := auth.UserID(ctx)
 	if err != nil {
-		return constant.ErrBadInput
+		return err
 	}
 
 	hashedPassBytes, err := bcrypt.GenerateFromPassword(
@@ -319,6 +644,7 @@ func (s *UserService) GrantNurseAccess(
 		return err
 	}
 
+	// the nurse has to replace a password someone else knows
 	_, err = s.userRepository.EditPassword(
 		ctx,
 		model.User{
@@ -326,45 +652,145 @@ func (s *UserService) GrantNurseAccess(
 			Password: string(
 				hashedPassBytes,
 			),
+			MustChangePassword: true,
+			HasAccess:          true,
+			UpdatedBy:          actorId,
+			UpdatedAt:          time.Now(),
 		},
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = s.tokenService.RevokeAll(
+		ctx,
+		user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   user.ID.String(),
+		},
+	)
 }
 
-func (s *UserService) UpdateNurse(
+// RevokeNurseAccess removes the password and single sign-on of a nurse.
+func (s *UserService) RevokeNurseAccess(
 	ctx context.Context,
-	user model.User,
-) (model.User, error) {
-	employeeId := ctx.Value("employeeId").(string)
-	err := util.ValidateUserEmployeeID(
-		employeeId,
+	id uuid.UUID,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.revokeNurseAccess(
+				ctx,
+				id,
+			)
+		},
+	)
+}
+
+func (s *UserService) revokeNurseAccess(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	savedNurse, err := s.userRepository.FindById(
+		ctx,
+		id,
 	)
 	if err != nil {
-		return model.User{}, err
+		return err
 	}
 
-	existingUser, err := s.userRepository.FindById(
+	if !savedNurse.HasRole(constant.RoleNurse) {
+		return constant.ErrNotFound
+	}
+
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.userRepository.EditPassword(
 		ctx,
-		user.ID,
+		model.User{
+			ID:        id,
+			HasAccess: false,
+			UpdatedBy: actorId,
+			UpdatedAt: time.Now(),
+		},
 	)
 	if err != nil {
-		return model.User{}, err
+		return err
 	}
 
-	err = util.ValidateIsANurse(
-		existingUser.EmployeeID,
+	err = s.userRepository.UnlinkIdentities(
+		ctx,
+		id,
 	)
 	if err != nil {
-		return model.User{}, err
+		return err
 	}
 
-	_, err = s.userRepository.FindByEmployeeId(
+	err = s.tokenService.RevokeAll(
 		ctx,
-		user.EmployeeID,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   id.String(),
+		},
+	)
+}
+
+func (s *UserService) UpdateNurse(
+	ctx context.Context,
+	user model.User,
+) (model.User, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.User, error) {
+			return s.updateNurse(
+				ctx,
+				user,
+			)
+		},
+	)
+}
+
+func (s *UserService) updateNurse(
+	ctx context.Context,
+	user model.User,
+) (model.User, error) {
+	existingUser, err := s.userRepository.FindById(
+		ctx,
+		user.ID,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if !existingUser.HasRole(constant.RoleNurse) {
+		return model.User{}, constant.ErrNotFound
+	}
+
+	sameNIPUser, err := s.userRepository.FindByEmployeeId(
+		ctx,
+		user.EmployeeID,
 	)
 	if err != nil {
 		if !errors.Is(
@@ -373,12 +799,19 @@ func (s *UserService) UpdateNurse(
 		) {
 			return model.User{}, err
 		}
-	} else {
+	} else if sameNIPUser.ID != existingUser.ID {
 		return model.User{}, constant.ErrConflict
 	}
 
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	existingUser.EmployeeID = user.EmployeeID
 	existingUser.Name = user.Name
+	existingUser.UpdatedBy = actorId
+	existingUser.UpdatedAt = time.Now()
 
 	saved, err := s.userRepository.Edit(
 		ctx,
@@ -388,6 +821,18 @@ func (s *UserService) UpdateNurse(
 		return model.User{}, err
 	}
 
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   saved.ID.String(),
+		},
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	return saved, nil
 }
 
@@ -395,14 +840,22 @@ func (s *UserService) DeleteNurse(
 	ctx context.Context,
 	id uuid.UUID,
 ) (model.User, error) {
-	employeeId := ctx.Value("employeeId").(string)
-	err := util.ValidateUserEmployeeID(
-		employeeId,
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.User, error) {
+			return s.deleteNurse(
+				ctx,
+				id,
+			)
+		},
 	)
-	if err != nil {
-		return model.User{}, err
-	}
+}
 
+func (s *UserService) deleteNurse(
+	ctx context.Context,
+	id uuid.UUID,
+) (model.User, error) {
 	existingUser, err := s.userRepository.FindById(
 		ctx,
 		id,
@@ -411,9 +864,11 @@ func (s *UserService) DeleteNurse(
 		return model.User{}, err
 	}
 
-	err = util.ValidateIsANurse(
-		existingUser.EmployeeID,
-	)
+	if !existingUser.HasRole(constant.RoleNurse) {
+		return model.User{}, constant.ErrNotFound
+	}
+
+	actorId, err := auth.UserID(ctx)
 	if err != nil {
 		return model.User{}, err
 	}
@@ -422,6 +877,7 @@ func (s *UserService) DeleteNurse(
 		ctx,
 		model.User{
 			ID:        id,
+			DeletedBy: actorId,
 			DeletedAt: time.Now(),
 		},
 	)
@@ -429,5 +885,379 @@ func (s *UserService) DeleteNurse(
 		return model.User{}, err
 	}
 
+	err = s.userRepository.UnlinkIdentities(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = s.tokenService.RevokeAll(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionDelete,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   id.String(),
+		},
+	)
+	if err != nil {
+		return model.User{}, err
+	}
+
 	return model.User{}, nil
 }
+
+// RestoreNurse undeletes a nurse without giving their access back.
+func (s *UserService) RestoreNurse(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.restoreNurse(
+				ctx,
+				id,
+			)
+		},
+	)
+}
+
+func (s *UserService) restoreNurse(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	deletedNurse, err := s.userRepository.FindDeletedById(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !deletedNurse.HasRole(constant.RoleNurse) {
+		return constant.ErrNotFound
+	}
+
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.userRepository.Restore(
+		ctx,
+		model.User{
+			ID:        id,
+			UpdatedBy: actorId,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionRestore,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   id.String(),
+		},
+	)
+}
+
+// PurgeNurse removes a deleted nurse, reassigning or anonymizing their authorship.
+func (s *UserService) PurgeNurse(
+	ctx context.Context,
+	id uuid.UUID,
+	purge model.NursePurge,
+) error {
+	return s.transactor.WithinTransaction(
+		ctx,
+		func(ctx context.Context) error {
+			return s.purgeNurse(
+				ctx,
+				id,
+				purge,
+			)
+		},
+	)
+}
+
+func (s *UserService) purgeNurse(
+	ctx context.Context,
+	id uuid.UUID,
+	purge model.NursePurge,
+) error {
+	deletedNurse, err := s.userRepository.FindDeletedById(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !deletedNurse.HasRole(constant.RoleNurse) {
+		return constant.ErrNotFound
+	}
+
+	if deletedNurse.EmployeeID != purge.EmployeeID {
+		var validation model.ValidationError
+		validation.Format(
+			"nip",
+			constant.FormatPurgedNIP,
+		)
+		return validation.Err()
+	}
+
+	actorId, err := auth.UserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purge.ReassignTo != uuid.Nil {
+		// only active users can take over
+		_, err = s.userRepository.FindById(
+			ctx,
+			purge.ReassignTo,
+		)
+		if err != nil {
+			if errors.Is(
+				err,
+				constant.ErrNotFound,
+			) {
+				return constant.ErrBadInput
+			}
+			return err
+		}
+
+		err = s.userRepository.ReassignAuthored(
+			ctx,
+			id,
+			purge.ReassignTo,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	authored, err := s.userRepository.HasAuthored(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if authored {
+		err = s.userRepository.Anonymize(
+			ctx,
+			model.User{
+				ID:        id,
+				UpdatedBy: actorId,
+				UpdatedAt: time.Now(),
+			},
+		)
+	} else {
+		err = s.userRepository.Purge(
+			ctx,
+			id,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionPurge,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   id.String(),
+		},
+	)
+}
+
+func (s *UserService) RefreshToken(
+	ctx context.Context,
+	refreshToken string,
+) (model.TokenResponseBody, error) {
+	tokens, err := s.tokenService.Refresh(
+		ctx,
+		refreshToken,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	return model.TokenResponseBody{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *UserService) Logout(
+	ctx context.Context,
+	refreshToken string,
+) error {
+	principal, err := auth.PrincipalFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.tokenService.Revoke(
+		ctx,
+		principal.Session(),
+		refreshToken,
+	)
+}
+
+// UnlockNurse lifts the lockout of a nurse's NIP.
+func (s *UserService) UnlockNurse(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	savedNurse, err := s.userRepository.FindById(
+		ctx,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !savedNurse.HasRole(constant.RoleNurse) {
+		return constant.ErrNotFound
+	}
+
+	err = s.throttleService.Reset(
+		ctx,
+		savedNurse.EmployeeID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUnlock,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   id.String(),
+		},
+	)
+}
+
+// ChangePassword ends every session of the user and returns a new token pair.
+func (s *UserService) ChangePassword(
+	ctx context.Context,
+	body model.PasswordChangeBody,
+) (model.TokenResponseBody, error) {
+	return inTransaction(
+		ctx,
+		s.transactor,
+		func(ctx context.Context) (model.TokenResponseBody, error) {
+			return s.changePassword(
+				ctx,
+				body,
+			)
+		},
+	)
+}
+
+func (s *UserService) changePassword(
+	ctx context.Context,
+	body model.PasswordChangeBody,
+) (model.TokenResponseBody, error) {
+	err := body.IsValid(s.passwordPolicy)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	userId, err := auth.UserID(ctx)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	savedUser, err := s.userRepository.FindById(
+		ctx,
+		userId,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(
+		[]byte(savedUser.Password),
+		[]byte(body.OldPassword),
+	)
+	if err != nil {
+		var validation model.ValidationError
+		validation.Format(
+			"oldPassword",
+			constant.FormatCurrentPassword,
+		)
+		return model.TokenResponseBody{}, validation.Err()
+	}
+
+	hashedPassBytes, err := bcrypt.GenerateFromPassword(
+		[]byte(body.NewPassword),
+		s.salt,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	savedUser.Password = string(hashedPassBytes)
+	savedUser.MustChangePassword = false
+	savedUser.UpdatedBy = userId
+	savedUser.UpdatedAt = time.Now()
+	_, err = s.userRepository.EditPassword(
+		ctx,
+		savedUser,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	err = s.tokenService.RevokeAll(
+		ctx,
+		userId,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+	savedUser.TokenVersion++
+
+	err = s.auditService.Record(
+		ctx,
+		model.AuditEvent{
+			Action:     constant.AuditActionUpdate,
+			TargetType: constant.AuditTargetUser,
+			TargetID:   userId.String(),
+		},
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	tokens, err := s.tokenService.Issue(
+		ctx,
+		savedUser,
+	)
+	if err != nil {
+		return model.TokenResponseBody{}, err
+	}
+
+	return model.TokenResponseBody{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
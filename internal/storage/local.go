@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalStorage struct {
+	dir       string
+	publicURL string
+}
+
+func NewLocalStorage(
+	dir string,
+	publicURL string,
+) (*LocalStorage, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		dir:       dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}, nil
+}
+
+func (s *LocalStorage) Dir() string {
+	return s.dir
+}
+
+func (s *LocalStorage) Put(
+	ctx context.Context,
+	key string,
+	contentType string,
+	body io.Reader,
+) (string, error) {
+	path := filepath.Join(
+		s.dir,
+		filepath.FromSlash(key),
+	)
+	err := os.MkdirAll(
+		filepath.Dir(path),
+		0o755,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	// renamed into place so a half written file is never served
+	file, err := os.CreateTemp(
+		filepath.Dir(path),
+		".upload-*",
+	)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, body)
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+	err = file.Close()
+	if err != nil {
+		return "", err
+	}
+	err = os.Chmod(file.Name(), 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return "", err
+	}
+
+	return s.publicURL + "/" + key, nil
+}
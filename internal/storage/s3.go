@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+// S3Storage signs path-style requests with AWS Signature Version 4.
+type S3Storage struct {
+	client          *http.Client
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	publicURL       string
+}
+
+func NewS3Storage(cfg config.StorageConfig) (*S3Storage, error) {
+	if cfg.S3Endpoint == "" ||
+		cfg.S3Bucket == "" ||
+		cfg.S3AccessKeyID == "" ||
+		cfg.S3SecretAccessKey == "" {
+		return nil, errors.New(
+			"s3 storage needs S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY",
+		)
+	}
+
+	endpoint, err := url.Parse(
+		strings.TrimRight(cfg.S3Endpoint, "/"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := cfg.StoragePublicURL
+	if publicURL == "" {
+		publicURL = endpoint.String() + "/" + cfg.S3Bucket
+	}
+
+	return &S3Storage{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		endpoint:        endpoint,
+		region:          cfg.S3Region,
+		bucket:          cfg.S3Bucket,
+		accessKeyID:     cfg.S3AccessKeyID,
+		secretAccessKey: cfg.S3SecretAccessKey,
+		publicURL:       strings.TrimRight(publicURL, "/"),
+	}, nil
+}
+
+func (s *S3Storage) Put(
+	ctx context.Context,
+	key string,
+	contentType string,
+	body io.Reader,
+) (string, error) {
+	// the payload hash is part of the signature
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	path := "/" + uriEncode(s.bucket, false) + "/" + uriEncode(key, true)
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPut,
+		s.endpoint.String()+path,
+		bytes.NewReader(payload),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(
+		req,
+		path,
+		payload,
+		time.Now().UTC(),
+	)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(
+			io.LimitReader(res.Body, 1024),
+		)
+		return "", fmt.Errorf(
+			"s3 put %s: %s: %s",
+			key,
+			res.Status,
+			message,
+		)
+	}
+
+	return s.publicURL + "/" + key, nil
+}
+
+func (s *S3Storage) sign(
+	req *http.Request,
+	path string,
+	payload []byte,
+	now time.Time,
+) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join(
+		[]string{
+			req.Method,
+			path,
+			"",
+			"content-type:" + req.Header.Get("Content-Type"),
+			"host:" + req.URL.Host,
+			"x-amz-content-sha256:" + payloadHash,
+			"x-amz-date:" + amzDate,
+			"",
+			signedHeaders,
+			payloadHash,
+		},
+		"\n",
+	)
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join(
+		[]string{
+			"AWS4-HMAC-SHA256",
+			amzDate,
+			scope,
+			sha256Hex([]byte(canonicalRequest)),
+		},
+		"\n",
+	)
+
+	signingKey := hmacSHA256(
+		[]byte("AWS4"+s.secretAccessKey),
+		date,
+	)
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(
+		hmacSHA256(signingKey, stringToSign),
+	)
+
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			s.accessKeyID,
+			scope,
+			signedHeaders,
+			signature,
+		),
+	)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(
+	key []byte,
+	data string,
+) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode escapes value for SigV4 canonical requests.
+func uriEncode(
+	value string,
+	keepSlash bool,
+) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z',
+			'a' <= b && b <= 'z',
+			'0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && keepSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return encoded.String()
+}
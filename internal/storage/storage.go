@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/nozzlium/halosuster/internal/config"
+)
+
+type Storage interface {
+	Put(
+		ctx context.Context,
+		key string,
+		contentType string,
+		body io.Reader,
+	) (string, error)
+}
+
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", "local":
+		return NewLocalStorage(
+			cfg.StorageLocalDir,
+			cfg.StoragePublicURL,
+		)
+	case "s3":
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf(
+			"unknown storage driver %q, expected local or s3",
+			cfg.StorageDriver,
+		)
+	}
+}
@@ -0,0 +1,118 @@
+// Package totp implements RFC 6238 with HMAC-SHA1, 6 digits and 30 second steps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Step   = 30 * time.Second
+	// Skew is how many steps a code may be off
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+func URL(
+	issuer string,
+	account string,
+	secret string,
+) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Step.Seconds())))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}).String()
+}
+
+func StepAt(t time.Time) int64 {
+	return t.Unix() / int64(Step.Seconds())
+}
+
+func Code(
+	secret string,
+	step int64,
+) (string, error) {
+	key, err := encoding.DecodeString(
+		strings.ToUpper(secret),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf(
+		"%0*d",
+		Digits,
+		value%1000000,
+	), nil
+}
+
+// Validate only matches steps after lastStep.
+func Validate(
+	secret string,
+	code string,
+	now time.Time,
+	lastStep int64,
+) (step int64, ok bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := StepAt(now)
+	for step := current - Skew; step <= current+Skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare(
+			[]byte(expected),
+			[]byte(code),
+		) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
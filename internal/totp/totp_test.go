@@ -0,0 +1,164 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed of RFC 6238 appendix B, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// the RFC lists 8 digit codes, these are their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := Code(
+			rfcSecret,
+			StepAt(time.Unix(test.unix, 0)),
+		)
+		if err != nil {
+			t.Fatalf("Code at %d: %v", test.unix, err)
+		}
+		if code != test.code {
+			t.Errorf("Code at %d = %s, want %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	code, err := Code(
+		"gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+		StepAt(time.Unix(59, 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Errorf("Code = %s, want 287082", code)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code(
+		"not base32!",
+		0,
+	)
+	if err == nil {
+		t.Error("Code accepted a secret that is not base32")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := StepAt(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{
+			name:     "current step",
+			code:     "050471",
+			lastStep: 0,
+			wantStep: current,
+			wantOK:   true,
+		},
+		{
+			name:     "previous step within skew",
+			code:     mustCode(t, current-1),
+			lastStep: 0,
+			wantStep: current - 1,
+			wantOK:   true,
+		},
+		{
+			name:     "next step within skew",
+			code:     mustCode(t, current+1),
+			lastStep: 0,
+			wantStep: current + 1,
+			wantOK:   true,
+		},
+		{
+			name:     "outside skew",
+			code:     mustCode(t, current-2),
+			lastStep: 0,
+		},
+		{
+			name:     "replayed step",
+			code:     "050471",
+			lastStep: current,
+		},
+		{
+			name:     "wrong code",
+			code:     "000000",
+			lastStep: 0,
+		},
+		{
+			name:     "wrong length",
+			code:     "05047",
+			lastStep: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			step, ok := Validate(
+				rfcSecret,
+				test.code,
+				now,
+				test.lastStep,
+			)
+			if ok != test.wantOK || step != test.wantStep {
+				t.Errorf(
+					"Validate = (%d, %t), want (%d, %t)",
+					step,
+					ok,
+					test.wantStep,
+					test.wantOK,
+				)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret has %d bytes, want 20", len(key))
+	}
+}
+
+func mustCode(t *testing.T, step int64) string {
+	t.Helper()
+
+	code, err := Code(
+		rfcSecret,
+		step,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
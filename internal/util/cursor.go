@@ -0,0 +1,124 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+const (
+	DefaultPageSize = 5
+	MaxPageSize     = 100
+)
+
+// Keyset is the sort column and unique tiebreaker a list is paged by.
+type Keyset struct {
+	Field          string
+	Column         string
+	Tiebreaker     string
+	TiebreakerType FieldType
+}
+
+type Cursor struct {
+	CreatedAt time.Time
+	ID        interface{}
+}
+
+type cursorPayload struct {
+	CreatedAt string `json:"t"`
+	ID        string `json:"id"`
+}
+
+func EncodeCursor(
+	createdAt time.Time,
+	id string,
+) string {
+	payload, _ := json.Marshal(cursorPayload{
+		CreatedAt: createdAt.Format(time.RFC3339Nano),
+		ID:        id,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(
+	value string,
+	keyset Keyset,
+) (*Cursor, error) {
+	invalid := formatError(
+		"cursor",
+		constant.FormatCursor,
+	)
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var payload cursorPayload
+	err = json.Unmarshal(raw, &payload)
+	if err != nil {
+		return nil, invalid
+	}
+
+	createdAt, err := time.Parse(
+		time.RFC3339Nano,
+		payload.CreatedAt,
+	)
+	if err != nil {
+		return nil, invalid
+	}
+
+	id, err := QueryField{Type: keyset.TiebreakerType}.
+		parse(payload.ID)
+	if err != nil || payload.ID == "" {
+		return nil, invalid
+	}
+
+	return &Cursor{
+		CreatedAt: createdAt,
+		ID:        id,
+	}, nil
+}
+
+func PageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultPageSize
+	case limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return limit
+	}
+}
+
+// TrimPage cuts the extra row fetched by ListQuery.Pagination.
+func TrimPage[T any](
+	items []T,
+	limit int,
+) ([]T, bool) {
+	size := PageSize(limit)
+	if len(items) > size {
+		return items[:size], true
+	}
+
+	return items, false
+}
+
+// numberPlaceholders returns the next parameter number after clause.
+func numberPlaceholders(
+	clause string,
+	next int,
+) (string, int) {
+	count := strings.Count(clause, "$%d")
+	numbers := make([]interface{}, count)
+	for i := range numbers {
+		numbers[i] = next + i
+	}
+
+	return fmt.Sprintf(clause, numbers...), next + count
+}
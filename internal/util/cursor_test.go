@@ -0,0 +1,156 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+var testKeyset = Keyset{
+	Field:          "createdAt",
+	Column:         "created_at",
+	Tiebreaker:     "id",
+	TiebreakerType: FieldUUID,
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 6, 1, 8, 30, 15, 123456789, time.UTC)
+	id := uuid.MustParse("018f9a4e-6b7c-7d2e-9f10-1a2b3c4d5e6f")
+
+	cursor, err := decodeCursor(
+		EncodeCursor(
+			createdAt,
+			id.String(),
+		),
+		testKeyset,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) {
+		t.Errorf("CreatedAt = %v, want %v", cursor.CreatedAt, createdAt)
+	}
+	if cursor.ID != id {
+		t.Errorf("ID = %v, want %v", cursor.ID, id)
+	}
+}
+
+func TestCursorRoundTripNumber(t *testing.T) {
+	keyset := testKeyset
+	keyset.TiebreakerType = FieldNumber
+
+	cursor, err := decodeCursor(
+		EncodeCursor(
+			time.Unix(1717230615, 0),
+			"42",
+		),
+		keyset,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ID != int64(42) {
+		t.Errorf("ID = %v, want 42", cursor.ID)
+	}
+}
+
+func TestCursorTampered(t *testing.T) {
+	encode := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{
+			name:   "not base64",
+			cursor: "not a cursor!",
+		},
+		{
+			name:   "padded base64",
+			cursor: base64.URLEncoding.EncodeToString([]byte(`{"t":"x"}`)),
+		},
+		{
+			name:   "not JSON",
+			cursor: encode("createdAt=2024-06-01"),
+		},
+		{
+			name:   "invalid time",
+			cursor: encode(`{"t":"yesterday","id":"018f9a4e-6b7c-7d2e-9f10-1a2b3c4d5e6f"}`),
+		},
+		{
+			name:   "missing time",
+			cursor: encode(`{"id":"018f9a4e-6b7c-7d2e-9f10-1a2b3c4d5e6f"}`),
+		},
+		{
+			name:   "invalid tiebreaker",
+			cursor: encode(`{"t":"2024-06-01T08:30:15Z","id":"1 or 1=1"}`),
+		},
+		{
+			name:   "missing tiebreaker",
+			cursor: encode(`{"t":"2024-06-01T08:30:15Z"}`),
+		},
+		{
+			name: "truncated",
+			cursor: EncodeCursor(
+				time.Unix(1717230615, 0),
+				"018f9a4e-6b7c-7d2e-9f10-1a2b3c4d5e6f",
+			)[:20],
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := decodeCursor(
+				test.cursor,
+				testKeyset,
+			)
+			if !errors.Is(err, constant.ErrBadInput) {
+				t.Errorf("decodeCursor = %v, want %v", err, constant.ErrBadInput)
+			}
+		})
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{limit: -1, want: DefaultPageSize},
+		{limit: 0, want: DefaultPageSize},
+		{limit: 1, want: 1},
+		{limit: MaxPageSize, want: MaxPageSize},
+		{limit: MaxPageSize + 1, want: MaxPageSize},
+		{limit: 1e9, want: MaxPageSize},
+	}
+
+	for _, test := range tests {
+		if got := PageSize(test.limit); got != test.want {
+			t.Errorf("PageSize(%d) = %d, want %d", test.limit, got, test.want)
+		}
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	page, hasMore := TrimPage(
+		[]int{1, 2, 3},
+		2,
+	)
+	if len(page) != 2 || !hasMore {
+		t.Errorf("TrimPage = (%v, %t), want 2 items and more", page, hasMore)
+	}
+
+	page, hasMore = TrimPage(
+		[]int{1, 2},
+		2,
+	)
+	if len(page) != 2 || hasMore {
+		t.Errorf("TrimPage = (%v, %t), want 2 items and no more", page, hasMore)
+	}
+}
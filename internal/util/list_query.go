@@ -0,0 +1,488 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+type FieldType int
+
+const (
+	FieldString FieldType = iota
+	FieldNumber
+	FieldTime
+	FieldUUID
+)
+
+type FilterOp string
+
+const (
+	FilterEq  FilterOp = "eq"
+	FilterNe  FilterOp = "ne"
+	FilterGt  FilterOp = "gt"
+	FilterGte FilterOp = "gte"
+	FilterLt  FilterOp = "lt"
+	FilterLte FilterOp = "lte"
+	// contains ignores case, prefix does not
+	FilterContains FilterOp = "contains"
+	FilterPrefix   FilterOp = "prefix"
+)
+
+var filterOpSQL = map[FilterOp]string{
+	FilterEq:  "=",
+	FilterNe:  "<>",
+	FilterGt:  ">",
+	FilterGte: ">=",
+	FilterLt:  "<",
+	FilterLte: "<=",
+}
+
+var RangeOps = []FilterOp{
+	FilterEq,
+	FilterGt,
+	FilterGte,
+	FilterLt,
+	FilterLte,
+}
+
+var EqualityOps = []FilterOp{
+	FilterEq,
+	FilterNe,
+}
+
+var TextOps = []FilterOp{
+	FilterEq,
+	FilterNe,
+	FilterContains,
+	FilterPrefix,
+}
+
+// QueryField whitelists an API field. Only Column is written into the SQL.
+type QueryField struct {
+	Column   string
+	Type     FieldType
+	Sortable bool
+	Ops      []FilterOp
+}
+
+type QueryFields map[string]QueryField
+
+type SortClause struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+type FilterClause struct {
+	Field  string
+	Column string
+	Op     FilterOp
+	Value  interface{}
+}
+
+// ListQuery is the parsed sort, `field[op]` filters, cursor and total.
+type ListQuery struct {
+	Sort      []SortClause
+	Filters   []FilterClause
+	Cursor    *Cursor
+	WithTotal bool
+}
+
+var filterKeyRegex = regexp.MustCompile(
+	`^([A-Za-z][A-Za-z0-9]*)\[([a-z]+)\]$`,
+)
+
+// QueryError matches constant.ErrBadInput with errors.Is.
+type QueryError struct {
+	Param  string
+	Code   string
+	Params map[string]interface{}
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf(
+		"%s: %s is %s %v",
+		constant.ErrBadInput,
+		e.Param,
+		e.Code,
+		e.Params,
+	)
+}
+
+func (e *QueryError) Is(target error) bool {
+	return target == constant.ErrBadInput
+}
+
+func formatError(
+	param string,
+	format string,
+) *QueryError {
+	return &QueryError{
+		Param: param,
+		Code:  constant.ValidationFormat,
+		Params: map[string]interface{}{
+			"format": format,
+		},
+	}
+}
+
+func oneOfError(
+	param string,
+	values []string,
+) *QueryError {
+	return &QueryError{
+		Param: param,
+		Code:  constant.ValidationOneOf,
+		Params: map[string]interface{}{
+			"values": values,
+		},
+	}
+}
+
+// ParseListQuery ignores keys without brackets, which are left to the caller.
+func ParseListQuery(
+	sort string,
+	queries map[string]string,
+	fields QueryFields,
+	keyset Keyset,
+) (ListQuery, error) {
+	var (
+		listQuery ListQuery
+		err       error
+	)
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sort, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			continue
+		}
+
+		desc := false
+		switch name[0] {
+		case '-':
+			desc = true
+			name = name[1:]
+		case '+':
+			name = name[1:]
+		}
+
+		field, ok := fields[name]
+		if !ok || !field.Sortable {
+			return ListQuery{}, oneOfError(
+				"sort",
+				fields.sortable(),
+			)
+		}
+		if seen[name] {
+			return ListQuery{}, formatError(
+				"sort",
+				constant.FormatSort,
+			)
+		}
+		seen[name] = true
+
+		listQuery.Sort = append(
+			listQuery.Sort,
+			SortClause{
+				Field:  name,
+				Column: field.Column,
+				Desc:   desc,
+			},
+		)
+	}
+
+	// sorted so the same request always renders the same SQL
+	keys := make([]string, 0, len(queries))
+	for key := range queries {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		rawValue := queries[key]
+		matches := filterKeyRegex.FindStringSubmatch(key)
+		if matches == nil {
+			if strings.ContainsAny(key, "[]") {
+				return ListQuery{}, formatError(
+					key,
+					constant.FormatFilter,
+				)
+			}
+			continue
+		}
+		name, op := matches[1], FilterOp(matches[2])
+
+		field, ok := fields[name]
+		if !ok {
+			return ListQuery{}, &QueryError{
+				Param: key,
+				Code:  constant.ValidationUnknown,
+			}
+		}
+		if !field.allows(op) {
+			return ListQuery{}, oneOfError(
+				key,
+				field.ops(name),
+			)
+		}
+
+		value, err := field.parse(rawValue)
+		if err != nil {
+			return ListQuery{}, formatError(
+				key,
+				field.format(),
+			)
+		}
+
+		listQuery.Filters = append(
+			listQuery.Filters,
+			FilterClause{
+				Field:  name,
+				Column: field.Column,
+				Op:     op,
+				Value:  value,
+			},
+		)
+	}
+
+	if total, ok := queries["total"]; ok {
+		withTotal, err := strconv.ParseBool(total)
+		if err != nil {
+			return ListQuery{}, oneOfError(
+				"total",
+				[]string{"true", "false"},
+			)
+		}
+		listQuery.WithTotal = withTotal
+	}
+
+	if cursor := queries["cursor"]; cursor != "" {
+		// a cursor only points into the keyset order
+		if !listQuery.keysetOrdered(keyset) {
+			return ListQuery{}, oneOfError(
+				"sort",
+				[]string{keyset.Field, "-" + keyset.Field},
+			)
+		}
+
+		listQuery.Cursor, err = decodeCursor(
+			cursor,
+			keyset,
+		)
+		if err != nil {
+			return ListQuery{}, err
+		}
+	}
+
+	return listQuery, nil
+}
+
+func (q ListQuery) WhereClauses() ([]string, []interface{}) {
+	clauses := make([]string, 0, len(q.Filters))
+	params := make([]interface{}, 0, len(q.Filters))
+	for _, filter := range q.Filters {
+		clauses = append(
+			clauses,
+			filter.clause(),
+		)
+		params = append(
+			params,
+			filter.Value,
+		)
+	}
+
+	return clauses, params
+}
+
+// OrderByClauses always ends with the keyset tiebreaker for a stable order.
+func (q ListQuery) OrderByClauses(
+	keyset Keyset,
+	defaultDesc bool,
+) []string {
+	if len(q.Sort) == 0 {
+		direction := sortDirection(defaultDesc)
+		return []string{
+			keyset.Column + " " + direction,
+			keyset.Tiebreaker + " " + direction,
+		}
+	}
+
+	clauses := make([]string, 0, len(q.Sort)+1)
+	for _, sort := range q.Sort {
+		clauses = append(
+			clauses,
+			sort.Column+" "+sortDirection(sort.Desc),
+		)
+	}
+	clauses = append(
+		clauses,
+		keyset.Tiebreaker+" "+sortDirection(q.Sort[len(q.Sort)-1].Desc),
+	)
+
+	return clauses
+}
+
+// CursorClause is ok when a cursor was given.
+func (q ListQuery) CursorClause(
+	keyset Keyset,
+	defaultDesc bool,
+) (clause string, params []interface{}, ok bool) {
+	if q.Cursor == nil {
+		return "", nil, false
+	}
+
+	op := ">"
+	if q.desc(defaultDesc) {
+		op = "<"
+	}
+
+	return fmt.Sprintf(
+		"(%s, %s) %s ($%%d, $%%d)",
+		keyset.Column,
+		keyset.Tiebreaker,
+		op,
+	), []interface{}{
+		q.Cursor.CreatedAt,
+		q.Cursor.ID,
+	}, true
+}
+
+// Pagination fetches one extra row for TrimPage.
+func (q ListQuery) Pagination(
+	limit, offset int,
+) (string, []interface{}) {
+	if q.Cursor != nil {
+		return " limit $%d ", []interface{}{
+			PageSize(limit) + 1,
+		}
+	}
+
+	return DefaultPaginationBuilder(
+		PageSize(limit)+1,
+		offset,
+	)
+}
+
+// NextCursor is empty on the last page or when not sorted by the keyset.
+func (q ListQuery) NextCursor(
+	keyset Keyset,
+	hasMore bool,
+	createdAt time.Time,
+	id string,
+) string {
+	if !hasMore || !q.keysetOrdered(keyset) {
+		return ""
+	}
+
+	return EncodeCursor(
+		createdAt,
+		id,
+	)
+}
+
+func (q ListQuery) keysetOrdered(keyset Keyset) bool {
+	return len(q.Sort) == 0 ||
+		(len(q.Sort) == 1 && q.Sort[0].Field == keyset.Field)
+}
+
+func (q ListQuery) desc(defaultDesc bool) bool {
+	if len(q.Sort) == 0 {
+		return defaultDesc
+	}
+
+	return q.Sort[0].Desc
+}
+
+func sortDirection(desc bool) string {
+	if desc {
+		return "desc"
+	}
+
+	return "asc"
+}
+
+func (f FilterClause) clause() string {
+	switch f.Op {
+	case FilterContains:
+		return fmt.Sprintf(
+			"%s ilike '%%%%' || $%%d || '%%%%'",
+			f.Column,
+		)
+	case FilterPrefix:
+		return fmt.Sprintf(
+			"%s like $%%d || '%%%%'",
+			f.Column,
+		)
+	default:
+		return fmt.Sprintf(
+			"%s %s $%%d",
+			f.Column,
+			filterOpSQL[f.Op],
+		)
+	}
+}
+
+func (f QueryFields) sortable() []string {
+	names := make([]string, 0, len(f))
+	for name, field := range f {
+		if field.Sortable {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func (f QueryField) ops(name string) []string {
+	keys := make([]string, 0, len(f.Ops))
+	for _, op := range f.Ops {
+		keys = append(keys, name+"["+string(op)+"]")
+	}
+
+	return keys
+}
+
+func (f QueryField) format() string {
+	switch f.Type {
+	case FieldNumber:
+		return constant.FormatNumber
+	case FieldTime:
+		return constant.FormatTime
+	case FieldUUID:
+		return constant.FormatUUID
+	default:
+		return constant.FormatFilter
+	}
+}
+
+func (f QueryField) allows(op FilterOp) bool {
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f QueryField) parse(value string) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		return strconv.ParseInt(value, 10, 64)
+	case FieldTime:
+		return time.Parse(time.RFC3339, value)
+	case FieldUUID:
+		return uuid.Parse(value)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nozzlium/halosuster/internal/constant"
+)
+
+var testFields = QueryFields{
+	"name": {
+		Column:   "name",
+		Type:     FieldString,
+		Sortable: true,
+		Ops:      TextOps,
+	},
+	"createdAt": {
+		Column:   "created_at",
+		Type:     FieldTime,
+		Sortable: true,
+		Ops:      RangeOps,
+	},
+	"age": {
+		Column: "age",
+		Type:   FieldNumber,
+		Ops:    EqualityOps,
+	},
+}
+
+func TestParseListQuery(t *testing.T) {
+	list, err := ParseListQuery(
+		"-createdAt, name",
+		map[string]string{
+			"name[contains]":     "siti",
+			"createdAt[gte]":     "2024-05-01T00:00:00Z",
+			"age[eq]":            "30",
+			"total":              "true",
+			"unbracketedIgnored": "x",
+		},
+		testFields,
+		testKeyset,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSort := []SortClause{
+		{Field: "createdAt", Column: "created_at", Desc: true},
+		{Field: "name", Column: "name"},
+	}
+	if !slices.Equal(list.Sort, wantSort) {
+		t.Errorf("Sort = %v, want %v", list.Sort, wantSort)
+	}
+	if !list.WithTotal {
+		t.Error("WithTotal = false, want true")
+	}
+
+	clauses, params := list.WhereClauses()
+	wantClauses := []string{
+		"age = $%d",
+		"created_at >= $%d",
+		"name ilike '%%' || $%d || '%%'",
+	}
+	if !slices.Equal(clauses, wantClauses) {
+		t.Errorf("clauses = %q, want %q", clauses, wantClauses)
+	}
+	if len(params) != 3 || params[0] != int64(30) || params[2] != "siti" {
+		t.Errorf("params = %v", params)
+	}
+}
+
+func TestParseListQueryErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      string
+		queries   map[string]string
+		wantParam string
+		wantCode  string
+	}{
+		{
+			name:      "unknown sort field",
+			sort:      "age",
+			wantParam: "sort",
+			wantCode:  constant.ValidationOneOf,
+		},
+		{
+			name:      "field sorted twice",
+			sort:      "name,-name",
+			wantParam: "sort",
+			wantCode:  constant.ValidationFormat,
+		},
+		{
+			name:      "malformed filter",
+			queries:   map[string]string{"name[eq": "siti"},
+			wantParam: "name[eq",
+			wantCode:  constant.ValidationFormat,
+		},
+		{
+			name:      "unknown filter field",
+			queries:   map[string]string{"password[eq]": "secret"},
+			wantParam: "password[eq]",
+			wantCode:  constant.ValidationUnknown,
+		},
+		{
+			name:      "unsupported operator",
+			queries:   map[string]string{"createdAt[contains]": "2024"},
+			wantParam: "createdAt[contains]",
+			wantCode:  constant.ValidationOneOf,
+		},
+		{
+			name:      "unparseable value",
+			queries:   map[string]string{"createdAt[gte]": "yesterday"},
+			wantParam: "createdAt[gte]",
+			wantCode:  constant.ValidationFormat,
+		},
+		{
+			name:      "invalid total",
+			queries:   map[string]string{"total": "maybe"},
+			wantParam: "total",
+			wantCode:  constant.ValidationOneOf,
+		},
+		{
+			name:      "cursor with another sort",
+			sort:      "name",
+			queries:   map[string]string{"cursor": "abc"},
+			wantParam: "sort",
+			wantCode:  constant.ValidationOneOf,
+		},
+		{
+			name:      "invalid cursor",
+			queries:   map[string]string{"cursor": "abc"},
+			wantParam: "cursor",
+			wantCode:  constant.ValidationFormat,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseListQuery(
+				test.sort,
+				test.queries,
+				testFields,
+				testKeyset,
+			)
+			if !errors.Is(err, constant.ErrBadInput) {
+				t.Fatalf("ParseListQuery = %v, want %v", err, constant.ErrBadInput)
+			}
+
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("ParseListQuery = %T, want *QueryError", err)
+			}
+			if queryErr.Param != test.wantParam || queryErr.Code != test.wantCode {
+				t.Errorf(
+					"QueryError = %s %s, want %s %s",
+					queryErr.Param,
+					queryErr.Code,
+					test.wantParam,
+					test.wantCode,
+				)
+			}
+		})
+	}
+}
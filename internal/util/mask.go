@@ -0,0 +1,28 @@
+package util
+
+import "strings"
+
+// Mask masks values too short to keep both ends entirely.
+func Mask(
+	value string,
+	head, tail int,
+) string {
+	runes := []rune(value)
+	if len(runes) <= head+tail {
+		return strings.Repeat("*", len(runes))
+	}
+
+	return string(runes[:head]) +
+		strings.Repeat("*", len(runes)-head-tail) +
+		string(runes[len(runes)-tail:])
+}
+
+// MaskIdentityNumber renders e.g. 3271********0001.
+func MaskIdentityNumber(identityNumber string) string {
+	return Mask(identityNumber, 4, 4)
+}
+
+// MaskPhoneNumber renders e.g. +62812****789.
+func MaskPhoneNumber(phoneNumber string) string {
+	return Mask(phoneNumber, 6, 3)
+}
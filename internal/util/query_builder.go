@@ -13,11 +13,17 @@ func BuildQueryStringAndParams(
 	noDeleted bool,
 ) (string, []interface{}) {
 	where, params := whereBuilder()
-	for i, clause := range where {
+	next := 1
+	for _, clause := range where {
+		var numbered string
+		numbered, next = numberPlaceholders(
+			clause,
+			next,
+		)
 		fmt.Fprintf(
 			baseQuery,
 			" and %s",
-			fmt.Sprintf(clause, i+1),
+			numbered,
 		)
 	}
 	if noDeleted {
@@ -47,12 +53,14 @@ func BuildQueryStringAndParams(
 	}
 
 	pagination, paginationParams := paginationBuilder()
-	paramsLen := len(params)
+	pagination, _ = numberPlaceholders(
+		pagination,
+		next,
+	)
 	fmt.Fprintf(
 		baseQuery,
-		fmt.Sprintf(" %s ", pagination),
-		paramsLen+1,
-		paramsLen+2,
+		" %s ",
+		pagination,
 	)
 	params = append(
 		params,
@@ -67,11 +75,17 @@ func BuildQueryStringAndParamsWithoutLimit(
 	orderByBuilder func() []string,
 ) (string, []interface{}) {
 	where, params := whereBuilder()
-	for i, clause := range where {
+	next := 1
+	for _, clause := range where {
+		var numbered string
+		numbered, next = numberPlaceholders(
+			clause,
+			next,
+		)
 		fmt.Fprintf(
 			baseQuery,
 			" and %s",
-			fmt.Sprintf(clause, i+1),
+			numbered,
 		)
 	}
 
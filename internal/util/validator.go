@@ -92,26 +92,6 @@ func ValidatePhoneNumber(
 	return nil
 }
 
-func ValidateIsANurse(
-	employeeId string,
-) error {
-	regex := "^[303]{3}[0-9]{10,12}$"
-	roleStringRegex, err := regexp.Compile(
-		regex,
-	)
-	if err != nil {
-		return err
-	}
-
-	if !roleStringRegex.MatchString(
-		employeeId,
-	) {
-		return constant.ErrNotFound
-	}
-
-	return nil
-}
-
 func ValidateGeneralEmployeeID(
 	employeeId string,
 ) error {
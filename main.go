@@ -1,96 +1,404 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net/url"
+	"os"
 
 	"github.com/bytedance/sonic"
 	"github.com/caarlos0/env/v11"
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/nozzlium/halosuster/internal/client"
 	"github.com/nozzlium/halosuster/internal/config"
+	"github.com/nozzlium/halosuster/internal/constant"
 	"github.com/nozzlium/halosuster/internal/handler"
+	"github.com/nozzlium/halosuster/internal/jwtkey"
 	"github.com/nozzlium/halosuster/internal/middleware"
+	"github.com/nozzlium/halosuster/internal/model"
+	"github.com/nozzlium/halosuster/internal/oidc"
+	"github.com/nozzlium/halosuster/internal/pii"
 	"github.com/nozzlium/halosuster/internal/repository"
 	"github.com/nozzlium/halosuster/internal/service"
+	"github.com/nozzlium/halosuster/internal/storage"
 )
 
 func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves the API when no subcommand is given.
+func run(args []string) error {
+	command := "serve"
+	if len(args) > 0 {
+		command = args[0]
+		args = args[1:]
+	}
+
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "migrate":
+		return runMigrate(args)
+	case "bootstrap-admin":
+		return runBootstrapAdmin(args)
+	case "pii":
+		return runPII(args)
+	default:
+		return fmt.Errorf(
+			"unknown command %q, expected one of: serve, migrate, bootstrap-admin, pii",
+			command,
+		)
+	}
+}
+
+func runServe(args []string) error {
+	flags := flag.NewFlagSet(
+		"serve",
+		flag.ContinueOnError,
+	)
+	migrateFirst := flags.Bool(
+		"migrate",
+		false,
+		"apply pending migrations and encrypt plaintext patients before serving",
+	)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := client.InitDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *migrateFirst {
+		err = migrateUp(db)
+		if err != nil {
+			return err
+		}
+
+		keyring, err := pii.NewKeyring(cfg.PII)
+		if err != nil {
+			return err
+		}
+		err = backfillPII(
+			repository.NewPatientRepository(
+				db,
+				keyring,
+			),
+			repository.NewAuditRepository(
+				db,
+				keyring,
+			),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	fiberApp := fiber.New(fiber.Config{
 		JSONEncoder: sonic.Marshal,
 		JSONDecoder: sonic.Unmarshal,
 		Prefork:     false,
 	})
 
-	err := setupApp(fiberApp)
+	err = setupApp(
+		fiberApp,
+		cfg,
+		db,
+	)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	err = fiberApp.Listen(":8080")
-	if err != nil {
-		log.Fatal(err)
-	}
+	return fiberApp.Listen(":8080")
 }
 
-func setupApp(app *fiber.App) error {
+func loadConfig() (config.Config, error) {
 	var cfg config.Config
 	opts := env.Options{
 		TagName: "json",
 	}
 	if err := env.ParseWithOptions(&cfg, opts); err != nil {
-		log.Fatalf("%+v\n", err)
-		return err
+		return cfg, err
 	}
 
-	db, err := client.InitDB(cfg.DB)
+	return cfg, nil
+}
+
+type services struct {
+	role      *repository.RoleRepository
+	token     *service.TokenService
+	audit     *service.AuditService
+	user      *service.UserService
+	twoFactor *service.TwoFactorService
+	oidc      *service.OIDCService
+	patient   *service.PatientService
+	record    *service.RecordService
+	image     *service.ImageService
+	storage   storage.Storage
+}
+
+func newServices(
+	cfg config.Config,
+	db *pgxpool.Pool,
+) (services, error) {
+	fileStorage, err := storage.New(
+		cfg.Storage,
+	)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return services{}, err
 	}
 
+	keyring, err := pii.NewKeyring(cfg.PII)
+	if err != nil {
+		return services{}, err
+	}
+
+	jwtKeyring, err := jwtkey.NewKeyring(cfg.JWT)
+	if err != nil {
+		return services{}, err
+	}
+
+	transactor := repository.NewTransactor(
+		db,
+	)
 	userRepo := repository.NewUserRepository(
 		db,
 	)
 	patientRepo := repository.NewPatientRepository(
 		db,
+		keyring,
 	)
 	recordRepo := repository.NewRecordRepository(
 		db,
+		keyring,
+	)
+	roleRepo := repository.NewRoleRepository(
+		db,
+	)
+	tokenRepo := repository.NewTokenRepository(
+		db,
+	)
+	auditRepo := repository.NewAuditRepository(
+		db,
+		keyring,
+	)
+	throttleRepo := repository.NewLoginThrottleRepository(
+		db,
+	)
+	twoFactorRepo := repository.NewTwoFactorRepository(
+		db,
+	)
+	oidcRepo := repository.NewOIDCRepository(
+		db,
 	)
 
+	auditService := service.NewAuditService(
+		auditRepo,
+	)
+	tokenService := service.NewTokenService(
+		transactor,
+		tokenRepo,
+		userRepo,
+		jwtKeyring,
+		cfg.JWT.JWTIssuer,
+		cfg.JWT.JWTAudience,
+		cfg.AccessTokenTTL,
+		cfg.RefreshTokenTTL,
+		cfg.TwoFactor.TwoFactorChallengeTTL,
+	)
+	throttleService := service.NewLoginThrottleService(
+		transactor,
+		throttleRepo,
+		cfg.Login,
+	)
+	twoFactorService := service.NewTwoFactorService(
+		transactor,
+		twoFactorRepo,
+		userRepo,
+		auditService,
+		cfg.TwoFactor.TOTPIssuer,
+	)
 	userService := service.NewUserService(
+		transactor,
 		userRepo,
+		roleRepo,
+		tokenService,
+		auditService,
+		throttleService,
+		twoFactorService,
+		cfg.PasswordPolicy,
 		int(cfg.BCryptSalt),
-		cfg.JWTSecret,
 	)
+	// single sign-on stays off without an issuer
+	var oidcService *service.OIDCService
+	if cfg.OIDC.OIDCIssuer != "" {
+		provider, err := oidc.NewProvider(cfg.OIDC)
+		if err != nil {
+			return services{}, err
+		}
+		oidcService = service.NewOIDCService(
+			transactor,
+			oidcRepo,
+			userRepo,
+			roleRepo,
+			auditService,
+			userService,
+			provider,
+			cfg.OIDC,
+		)
+	}
 	patientService := service.NewPatientService(
+		transactor,
 		patientRepo,
+		recordRepo,
+		auditService,
 	)
 	recordService := service.NewRecordService(
+		transactor,
 		recordRepo,
+		auditService,
+	)
+	imageService := service.NewImageService(
+		fileStorage,
+		cfg.Storage.ImageMaxSize,
+	)
+
+	return services{
+		role:      roleRepo,
+		token:     tokenService,
+		audit:     auditService,
+		user:      userService,
+		twoFactor: twoFactorService,
+		oidc:      oidcService,
+		patient:   patientService,
+		record:    recordService,
+		image:     imageService,
+		storage:   fileStorage,
+	}, nil
+}
+
+func setupApp(
+	app *fiber.App,
+	cfg config.Config,
+	db *pgxpool.Pool,
+) error {
+	svc, err := newServices(
+		cfg,
+		db,
+	)
+	if err != nil {
+		return err
+	}
+	tokenService := svc.token
+
+	roles, err := svc.role.FindAll(
+		context.Background(),
 	)
+	if err != nil {
+		return err
+	}
+	// role_permissions is only read here, changes to it need a restart
+	permissions := model.NewPermissionSet(roles)
 
 	userHandler := handler.NewUserHandler(
-		userService,
+		svc.user,
 	)
 	patientHandler := handler.NewPatientHandler(
-		patientService,
+		svc.patient,
 	)
 	recordHandler := handler.NewRecordHandler(
-		recordService,
+		svc.record,
+	)
+	auditHandler := handler.NewAuditHandler(
+		svc.audit,
+	)
+	imageHandler := handler.NewImageHandler(
+		svc.image,
+	)
+	twoFactorHandler := handler.NewTwoFactorHandler(
+		svc.twoFactor,
+	)
+	keyHandler := handler.NewKeyHandler(
+		tokenService,
+	)
+
+	app.Use(middleware.RequestInfo())
+
+	app.Get(
+		"/.well-known/jwks.json",
+		keyHandler.JWKS,
 	)
 
+	// served under the path of their public URL
+	if localStorage, ok := svc.storage.(*storage.LocalStorage); ok {
+		publicURL, err := url.Parse(
+			cfg.Storage.StoragePublicURL,
+		)
+		if err != nil {
+			return err
+		}
+		app.Static(
+			publicURL.Path,
+			localStorage.Dir(),
+		)
+	}
+
 	v1 := app.Group("/v1")
 
 	userIt := v1.Group("/user/it")
 	userIt.Post(
 		"/register",
+		middleware.Protected(tokenService),
+		middleware.SetClaimsData(),
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionUserManage,
+		),
 		userHandler.Register,
 	)
 	userIt.Post(
 		"/login",
 		userHandler.Login,
 	)
+	userIt.Post(
+		"/login/2fa",
+		userHandler.LoginTwoFactor,
+	)
+
+	userItTwoFactor := v1.Group("/user/it/2fa")
+	userItTwoFactor.Use(middleware.Protected(tokenService)).
+		Use(middleware.SetClaimsData())
+	userItTwoFactor.Post(
+		"/enroll",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionTwoFactor,
+		),
+		twoFactorHandler.Enroll,
+	)
+	userItTwoFactor.Post(
+		"/verify",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionTwoFactor,
+		),
+		twoFactorHandler.Verify,
+	)
 
 	userNurse := v1.Group("/user/nurse")
 	userNurse.Post(
@@ -98,52 +406,238 @@ func setupApp(app *fiber.App) error {
 		userHandler.LoginNurse,
 	)
 	userNurseProtected := userNurse.
-		Use(middleware.Protected()).
+		Use(middleware.Protected(tokenService)).
 		Use(middleware.SetClaimsData())
 	userNurseProtected.Post(
 		"/register",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
 		userHandler.RegisterNurse,
 	)
 	userNurseProtected.Put(
 		"/:userId",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
 		userHandler.Update,
 	)
 	userNurseProtected.Delete(
 		"/:userId",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
 		userHandler.Delete,
 	)
 	userNurseProtected.Post(
 		"/:userId/access",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
 		userHandler.GrantNurseAccess,
 	)
+	userNurseProtected.Delete(
+		"/:userId/access",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
+		userHandler.RevokeNurseAccess,
+	)
+	userNurseProtected.Post(
+		"/:userId/unlock",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
+		userHandler.Unlock,
+	)
+	userNurseProtected.Post(
+		"/:userId/restore",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionNurseManage,
+		),
+		userHandler.Restore,
+	)
+	userNurseProtected.Post(
+		"/:userId/purge",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionUserPurge,
+		),
+		userHandler.Purge,
+	)
+
+	if svc.oidc != nil {
+		oidcHandler := handler.NewOIDCHandler(
+			svc.oidc,
+		)
+		userOIDC := v1.Group("/user/oidc")
+		userOIDC.Get(
+			"/login",
+			oidcHandler.Login,
+		)
+		userOIDC.Get(
+			"/callback",
+			oidcHandler.Callback,
+		)
+	}
+
+	userToken := v1.Group("/user/token")
+	userToken.Post(
+		"/refresh",
+		userHandler.RefreshToken,
+	)
 
 	user := v1.Group("/user")
-	user.Use(middleware.Protected())
-	user.Get("", userHandler.FindAll)
+	user.Use(middleware.Protected(tokenService)).
+		Use(middleware.SetClaimsData())
+	user.Post(
+		"/logout",
+		userHandler.Logout,
+	)
+	// open to restricted tokens, which carry no role
+	user.Put(
+		"/me/password",
+		userHandler.ChangePassword,
+	)
+	user.Get(
+		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionUserRead,
+		),
+		userHandler.FindAll,
+	)
 
 	patient := v1.Group(
 		"/medical/patient",
 	)
-	patient.Use(middleware.Protected()).
+	patient.Use(middleware.Protected(tokenService)).
 		Use(middleware.SetClaimsData())
 	patient.Post(
 		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientWrite,
+		),
 		patientHandler.Create,
 	)
 	patient.Get(
 		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientRead,
+		),
 		patientHandler.FindAll,
 	)
+	patient.Get(
+		"/:identityNumber",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientRead,
+		),
+		patientHandler.FindDetail,
+	)
+	patient.Put(
+		"/:identityNumber",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientWrite,
+		),
+		patientHandler.Update,
+	)
+	patient.Delete(
+		"/:identityNumber",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientWrite,
+		),
+		patientHandler.Delete,
+	)
+	patient.Post(
+		"/:identityNumber/restore",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientWrite,
+		),
+		patientHandler.Restore,
+	)
+	patient.Post(
+		"/:identityNumber/reveal",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionPatientReveal,
+		),
+		patientHandler.Reveal,
+	)
 
 	record := v1.Group(
 		"/medical/record",
 	)
-	record.Use(middleware.Protected()).
+	record.Use(middleware.Protected(tokenService)).
 		Use(middleware.SetClaimsData())
 	record.Post(
 		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionRecordWrite,
+		),
 		recordHandler.Create,
 	)
+	record.Get(
+		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionRecordRead,
+		),
+		recordHandler.FindAll,
+	)
+	record.Get(
+		"/:id/revisions",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionRecordRead,
+		),
+		recordHandler.FindRevisions,
+	)
+	record.Post(
+		"/:id/revisions",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionRecordWrite,
+		),
+		recordHandler.Amend,
+	)
+
+	audit := v1.Group("/audit")
+	audit.Use(middleware.Protected(tokenService)).
+		Use(middleware.SetClaimsData())
+	audit.Get(
+		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionAuditRead,
+		),
+		auditHandler.FindAll,
+	)
+
+	image := v1.Group("/image")
+	image.Use(middleware.Protected(tokenService)).
+		Use(middleware.SetClaimsData())
+	image.Post(
+		"",
+		middleware.RequirePermission(
+			permissions,
+			constant.PermissionImageUpload,
+		),
+		imageHandler.Upload,
+	)
 
 	return nil
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nozzlium/halosuster/db"
+	"github.com/nozzlium/halosuster/internal/client"
+	"github.com/nozzlium/halosuster/internal/migration"
+)
+
+// runMigrate handles `halosuster migrate up|down [steps]|status`.
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet(
+		"migrate",
+		flag.ContinueOnError,
+	)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New(
+			"usage: halosuster migrate up|down [steps]|status",
+		)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	pool, err := client.InitDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator, err := migration.New(
+		pool,
+		db.PrimaryMigrations,
+		db.PrimaryMigrationsDir,
+	)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("applied %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Print("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", flags.Arg(1))
+			}
+		}
+		reverted, err := migrator.Down(
+			ctx,
+			steps,
+		)
+		for _, m := range reverted {
+			log.Printf("reverted %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf(
+				"%-8s %d_%s\n",
+				state,
+				status.Migration.Version,
+				status.Migration.Name,
+			)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", flags.Arg(0))
+	}
+
+	return nil
+}
+
+func migrateUp(pool *pgxpool.Pool) error {
+	migrator, err := migration.New(
+		pool,
+		db.PrimaryMigrations,
+		db.PrimaryMigrationsDir,
+	)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(
+		context.Background(),
+	)
+	for _, m := range applied {
+		log.Printf("applied %d_%s", m.Version, m.Name)
+	}
+
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/nozzlium/halosuster/internal/client"
+	"github.com/nozzlium/halosuster/internal/pii"
+	"github.com/nozzlium/halosuster/internal/repository"
+)
+
+// runPII handles `halosuster pii backfill|rotate [-batch n]`.
+func runPII(args []string) error {
+	flags := flag.NewFlagSet(
+		"pii",
+		flag.ContinueOnError,
+	)
+	batchSize := flags.Int(
+		"batch",
+		100,
+		"patients handled per transaction",
+	)
+	if len(args) == 0 {
+		return errors.New(
+			"usage: halosuster pii backfill|rotate [-batch n]",
+		)
+	}
+	command := args[0]
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("invalid batch size %d", *batchSize)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	keyring, err := pii.NewKeyring(cfg.PII)
+	if err != nil {
+		return err
+	}
+
+	pool, err := client.InitDB(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	patientRepo := repository.NewPatientRepository(
+		pool,
+		keyring,
+	)
+	auditRepo := repository.NewAuditRepository(
+		pool,
+		keyring,
+	)
+
+	switch command {
+	case "backfill":
+		total, err := inBatches(
+			*batchSize,
+			patientRepo.EncryptPlaintext,
+		)
+		log.Printf("encrypted %d patients", total)
+		if err != nil {
+			return err
+		}
+		total, err = inBatches(
+			*batchSize,
+			auditRepo.IndexPatientTargets,
+		)
+		log.Printf("indexed %d patient audit events", total)
+		return err
+	case "rotate":
+		total, err := inBatches(
+			*batchSize,
+			patientRepo.RewrapDataKeys,
+		)
+		log.Printf(
+			"rewrapped %d data keys with key version %d",
+			total,
+			keyring.ActiveVersion(),
+		)
+		return err
+	default:
+		return fmt.Errorf("unknown pii command %q", command)
+	}
+}
+
+// inBatches returns how many rows step handled in total.
+func inBatches(
+	batchSize int,
+	step func(ctx context.Context, batchSize int) (int, error),
+) (int, error) {
+	var total int
+	for {
+		n, err := step(
+			context.Background(),
+			batchSize,
+		)
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+// backfillPII encrypts the patients left in plaintext and re-keys their audit events.
+func backfillPII(
+	patientRepo *repository.PatientRepository,
+	auditRepo *repository.AuditRepository,
+) error {
+	total, err := inBatches(
+		100,
+		patientRepo.EncryptPlaintext,
+	)
+	if total > 0 {
+		log.Printf("encrypted %d patients", total)
+	}
+	if err != nil {
+		return err
+	}
+
+	total, err = inBatches(
+		100,
+		auditRepo.IndexPatientTargets,
+	)
+	if total > 0 {
+		log.Printf("indexed %d patient audit events", total)
+	}
+
+	return err
+}